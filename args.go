@@ -0,0 +1,251 @@
+package optsparser
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// argDescr describes a declared positional argument
+type argDescr struct {
+	name		string
+	usage		string
+	dflt		string	// default value of the optional argument
+	min			int		// minimal number of values, 0 for optional arguments
+	max			int		// maximal number of values, 0 if unlimited
+	variadic	bool
+	set			func(values []string) []error
+}
+
+// AddArg declares a required positional argument with the name, e.g. "SRC", and the usage string.
+// The argument val points to a variable in which to store the value of the argument. Positional
+// arguments are bound to declared arguments in order of the declaration after the options are parsed,
+// they remain available by the Args method. If positional arguments are declared, a missing or an
+// extra argument fails the parsing with [*ArgCountError], an invalid value fails it with [*InvalidArgError].
+// Declared arguments are printed in the synopsis and in the "Arguments" section of the Usage output.
+//
+// AddArg panics if the name is empty or already declared, the parser has commands, or an optional
+// or a variadic argument was declared before.
+func AddArg[T Scalar](p *OptsParser, name, usage string, val *T) {
+	p.addArg(&argDescr{name: name, usage: usage, min: 1, max: 1, set: setArg(name, val)})
+}
+
+// AddOptArg declares an optional positional argument, like [AddArg] does, the variable pointed
+// by val is set to dfltVal if the argument is omitted. AddOptArg panics if the name is empty or already
+// declared, the parser has commands, or a variadic argument was declared before.
+func AddOptArg[T Scalar](p *OptsParser, name, usage string, val *T, dfltVal T) {
+	set := setArg(name, val)
+	p.addArg(&argDescr{
+		name:	name,
+		usage:	usage,
+		dflt:	formatAny(dfltVal),
+		max:	1,
+		set:	func(values []string) []error {
+			if len(values) == 0 {
+				*val = dfltVal
+				return nil
+			}
+			return set(values)
+		},
+	})
+}
+
+// AddArgs declares a variadic positional argument, e.g. "FILES", which takes all remaining positional
+// arguments, at least min and at most max of them, if max is 0 the number of values is unlimited. The argument
+// val points to a slice in which to store the values. The variadic argument must be the last one. AddArgs
+// panics if the name is empty or already declared, the parser has commands, the variadic argument was
+// declared before, min is negative or max is less than min.
+func AddArgs[T Scalar](p *OptsParser, name, usage string, val *[]T, min, max int) {
+	if min < 0 || (max != 0 && max < min) {
+		doPanic("Invalid number of values of argument %q: min %d, max %d", name, min, max)
+	}
+
+	parse, _ := scalarParser[T]()
+	p.addArg(&argDescr{
+		name:	name,
+		usage:	usage,
+		min:		min,
+		max:		max,
+		variadic:	true,
+		set:		func(values []string) []error {
+			errs := []error{}
+			items := make([]T, 0, len(values))
+			for _, value := range values {
+				v, err := parse(value)
+				if err != nil {
+					errs = append(errs, &InvalidArgError{Arg: name, Value: value, Cause: err})
+					continue
+				}
+				items = append(items, v)
+			}
+
+			if len(errs) == 0 {
+				*val = items
+			}
+
+			return errs
+		},
+	})
+}
+
+// setArg returns the function that sets the value of the scalar argument
+func setArg[T Scalar](name string, val *T) func([]string) []error {
+	parse, _ := scalarParser[T]()
+
+	return func(values []string) []error {
+		v, err := parse(values[0])
+		if err != nil {
+			return []error{&InvalidArgError{Arg: name, Value: values[0], Cause: err}}
+		}
+		*val = v
+
+		return nil
+	}
+}
+
+func (p *OptsParser) addArg(arg *argDescr) {
+	if arg.name == "" {
+		doPanic("Argument name cannot be empty")
+	}
+	if len(p.commands) != 0 {
+		doPanic("Cannot declare argument %q - parser has commands", arg.name)
+	}
+
+	for _, a := range p.args {
+		switch {
+		case a.name == arg.name:
+			doPanic("Argument %q is already declared", arg.name)
+		case a.variadic:
+			doPanic("Cannot declare argument %q after variadic argument %q", arg.name, a.name)
+		case a.min == 0 && arg.min != 0:
+			doPanic("Cannot declare required argument %q after optional argument %q", arg.name, a.name)
+		}
+	}
+
+	p.args = append(p.args, arg)
+}
+
+// argsErrors binds positional arguments to declared arguments and returns errors of arguments
+func (p *OptsParser) argsErrors() []error {
+	if len(p.args) == 0 {
+		// Positional arguments are not declared, nothing to check
+		return nil
+	}
+
+	values := p.Args()
+	errs := []error{}
+
+	// Check the number of arguments
+	min, max := p.argsRange()
+	switch {
+	case len(values) < min:
+		errs = append(errs, &ArgCountError{Arg: p.missingArg(len(values)), Min: min, Max: max, Got: len(values)})
+	case max != 0 && len(values) > max:
+		errs = append(errs, &ArgCountError{Min: min, Max: max, Got: len(values)})
+	}
+
+	// Bind available values to arguments
+	for _, arg := range p.args {
+		n := len(values)
+		if arg.max != 0 && n > arg.max {
+			n = arg.max
+		}
+		if n < arg.min {
+			// Argument is missing, it is already reported
+			continue
+		}
+
+		errs = append(errs, arg.set(values[:n])...)
+		values = values[n:]
+	}
+
+	return errs
+}
+
+// argsRange returns the minimal and the maximal number of positional arguments, max is 0 if unlimited
+func (p *OptsParser) argsRange() (int, int) {
+	min, max := 0, 0
+	for _, arg := range p.args {
+		min += arg.min
+		max += arg.max
+		if arg.max == 0 {
+			// Variadic argument is the last
+			return min, 0
+		}
+	}
+
+	return min, max
+}
+
+// missingArg returns the name of the first argument for which got values are not enough
+func (p *OptsParser) missingArg(got int) string {
+	for _, arg := range p.args {
+		if got < arg.min {
+			return arg.name
+		}
+		got -= arg.min
+	}
+
+	// Unreachable if the number of arguments is less than minimal
+	return ""
+}
+
+// argsSynopsis returns the list of declared arguments for the synopsis, e.g. "SRC [DST] [FILES...]"
+func (p *OptsParser) argsSynopsis() string {
+	items := make([]string, 0, len(p.args))
+	for _, arg := range p.args {
+		item := arg.name
+		if arg.variadic {
+			item += "..."
+		}
+		if arg.min == 0 {
+			item = "[" + item + "]"
+		}
+		items = append(items, item)
+	}
+
+	return strings.Join(items, " ")
+}
+
+// descrArgs writes references for each declared argument
+func (p *OptsParser) descrArgs(out io.Writer) {
+	if len(p.args) == 0 {
+		return
+	}
+
+	names := make([]string, 0, len(p.args))
+	for _, arg := range p.args {
+		names = append(names, arg.name)
+	}
+	col := p.column(names)
+
+	fmt.Fprintf(out, "\nArguments:\n")
+	for _, arg := range p.args {
+		// Additional notes about the argument
+		notes := []string{}
+		switch {
+		case !arg.variadic && arg.min == 0:
+			dflt := arg.dflt
+			if dflt == "" {
+				// Replace by quotes
+				dflt = `""`
+			}
+			notes = append(notes, "optional", "default: " + dflt)
+		case arg.variadic:
+			if arg.min != 0 {
+				notes = append(notes, fmt.Sprintf("at least %d", arg.min))
+			} else {
+				notes = append(notes, "optional")
+			}
+			if arg.max != 0 {
+				notes = append(notes, fmt.Sprintf("at most %d", arg.max))
+			}
+		}
+
+		descr := arg.usage
+		if len(notes) != 0 {
+			descr += " (" + strings.Join(notes, ", ") + ")"
+		}
+		fmt.Fprint(out, p.layoutEntry(arg.name, descr, col))
+	}
+}
@@ -0,0 +1,230 @@
+package optsparser
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testArgsOpts struct {
+	verbose	bool
+	src		string
+	dst		string
+	count	int
+	files	[]string
+}
+
+// argsOpts adds options and positional arguments used by tests
+func argsOpts(p *OptsParser, to *testArgsOpts) *OptsParser {
+	p.AddBool("verbose|v", "verbose output", &to.verbose, false)
+
+	AddArg(p, "SRC", "source file", &to.src)
+	AddOptArg(p, "DST", "destination directory", &to.dst, ".")
+	AddOptArg(p, "COUNT", "number of copies", &to.count, 1)
+	AddArgs(p, "FILES", "additional files", &to.files, 0, 2)
+
+	return p
+}
+
+func TestArgs(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct{
+		args	[]string
+		want	testArgsOpts
+	}{
+		`required-only`:	{
+			args:	[]string{`-v`, `a.txt`},
+			want:	testArgsOpts{verbose: true, src: `a.txt`, dst: `.`, count: 1, files: []string{}},
+		},
+		`optional`:			{
+			args:	[]string{`a.txt`, `/tmp`},
+			want:	testArgsOpts{src: `a.txt`, dst: `/tmp`, count: 1, files: []string{}},
+		},
+		`all`:				{
+			args:	[]string{`a.txt`, `/tmp`, `3`, `b.txt`, `c.txt`},
+			want:	testArgsOpts{src: `a.txt`, dst: `/tmp`, count: 3, files: []string{`b.txt`, `c.txt`}},
+		},
+	}
+
+	for testN, test := range tests {
+		to := testArgsOpts{}
+		p := argsOpts(newTestParser(nil), &to)
+		if err := p.ParseArgs(test.args); err != nil {
+			t.Errorf("%q parse failed: %v", testN, err)
+			continue
+		}
+		if !reflect.DeepEqual(to, test.want) {
+			t.Errorf("%q incorrect Parse result: want - %#v got - %#v", testN, test.want, to)
+		}
+	}
+
+	// Values are still available by Args
+	p := argsOpts(newTestParser(nil), &testArgsOpts{})
+	if err := p.ParseArgs([]string{`a.txt`, `/tmp`}); err != nil || !reflect.DeepEqual(p.Args(), []string{`a.txt`, `/tmp`}) {
+		t.Errorf("unexpected result: %v, %#v", err, p.Args())
+	}
+
+	// Unlimited variadic argument of non-string type
+	var delays []time.Duration
+	p = newTestParser(nil)
+	AddArgs(p, "DELAYS", "delays", &delays, 1, 0)
+	if err := p.ParseArgs([]string{`1s`, `2m`, `3h`, `4ms`}); err != nil {
+		t.Errorf("parse failed: %v", err)
+	}
+	if want := []time.Duration{time.Second, 2 * time.Minute, 3 * time.Hour, 4 * time.Millisecond}; !reflect.DeepEqual(delays, want) {
+		t.Errorf("incorrect values: want - %v got - %v", want, delays)
+	}
+}
+
+func TestArgsFail(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct{
+		args	[]string
+		want	error
+		text	string
+	}{
+		`missing`:		{
+			args:	[]string{`-v`},
+			want:	&ArgCountError{Arg: `SRC`, Min: 1, Max: 5, Got: 0},
+			text:	`missing argument SRC (expected at least 1 argument(s), got 0)`,
+		},
+		`too-many`:		{
+			args:	[]string{`a`, `b`, `1`, `c`, `d`, `e`},
+			want:	&ArgCountError{Min: 1, Max: 5, Got: 6},
+			text:	`too many arguments (expected at most 5 argument(s), got 6)`,
+		},
+		`invalid`:		{
+			args:	[]string{`a`, `b`, `x`},
+			want:	&InvalidArgError{Arg: `COUNT`, Value: `x`, Cause: errParse},
+			text:	`invalid value "x" for argument COUNT: parse error`,
+		},
+	}
+
+	for testN, test := range tests {
+		err := argsOpts(newTestParser(nil), &testArgsOpts{}).ParseArgs(test.args)
+		if !reflect.DeepEqual(err, test.want) {
+			t.Errorf("%q unexpected error: want - %#v got - %#v", testN, test.want, err)
+			continue
+		}
+		if err.Error() != test.text {
+			t.Errorf("%q unexpected error: want - %q got - %q", testN, test.text, err.Error())
+		}
+	}
+
+	// Missing values of variadic argument
+	var files []string
+	p := newTestParser(nil)
+	AddArg(p, "DST", "destination", new(string))
+	AddArgs(p, "FILES", "files", &files, 2, 0)
+	err := p.ParseArgs([]string{`/tmp`, `a`})
+	if want := (&ArgCountError{Arg: `FILES`, Min: 3, Got: 2}); !reflect.DeepEqual(err, want) {
+		t.Errorf("unexpected error: want - %#v got - %#v", want, err)
+	}
+
+	// All errors are reported in the report all mode
+	p = argsOpts(newTestParser(nil), &testArgsOpts{}).SetReportAll(true)
+	err = p.ParseArgs([]string{`--unknown`, `a`, `b`, `x`, `c`, `d`, `e`})
+
+	var errs ParseErrors
+	if !errors.As(err, &errs) {
+		t.Errorf("unexpected error type: want - %T got - %T (%v)", errs, err, err)
+		t.FailNow()
+	}
+
+	want := []string{
+		`flag provided but not defined: --unknown`,
+		`too many arguments (expected at most 5 argument(s), got 6)`,
+		`invalid value "x" for argument COUNT: parse error`,
+	}
+	got := make([]string, 0, len(errs))
+	for _, e := range errs {
+		got = append(got, e.Error())
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("incorrect list of errors:\nwant - %#v\ngot  - %#v", want, got)
+	}
+}
+
+func TestArgsUsage(t *testing.T) {
+	t.Parallel()
+
+	tOut := &bytes.Buffer{}
+	p := argsOpts(newParser(stubApp), &testArgsOpts{}).SetOutput(tOut)
+	p.WriteUsage(tOut)
+
+	want := `
+Usage of ` + stubApp + `:
+    ` + stubApp + ` [options] SRC [DST] [COUNT] [FILES...]
+    --verbose[=true|false], -v[=true|false]
+      verbose output (default: false)
+
+Arguments:
+    SRC
+      source file
+    DST
+      destination directory (optional, default: .)
+    COUNT
+      number of copies (optional, default: 1)
+    FILES
+      additional files (optional, at most 2)
+`
+	if tOut.String() != want {
+		t.Errorf("incorrect usage output: want -\n%s\ngot -\n%s", want, tOut.String())
+	}
+
+	// Command without options
+	tOut.Reset()
+	p = newParser(stubApp).SetOutput(tOut)
+	AddArgs(p, "FILES", "files to process", new([]string), 1, 0)
+	p.WriteUsage(tOut)
+
+	want = `
+Usage of ` + stubApp + `:
+    ` + stubApp + ` FILES...
+
+Arguments:
+    FILES
+      files to process (at least 1)
+`
+	if tOut.String() != want {
+		t.Errorf("incorrect usage output: want -\n%s\ngot -\n%s", want, tOut.String())
+	}
+}
+
+func TestArgsPanic(t *testing.T) {
+	t.Parallel()
+
+	for testN, add := range map[string]func(p *OptsParser){
+		`empty-name`:			func(p *OptsParser) { AddArg(p, "", "empty", new(string)) },
+		`duplicate`:			func(p *OptsParser) { AddArg(p, "SRC", "source", new(string)) },
+		`after-variadic`:		func(p *OptsParser) { AddOptArg(p, "MODE", "mode", new(string), "") },
+		`required-after-opt`:	func(p *OptsParser) {
+			q := newParser(stubApp)
+			AddOptArg(q, "DST", "destination", new(string), "")
+			AddArg(q, "SRC", "source", new(string))
+		},
+		`invalid-range`:		func(p *OptsParser) {
+			AddArgs(newParser(stubApp), "FILES", "files", new([]string), 3, 2)
+		},
+		`with-commands`:		func(p *OptsParser) {
+			q := newParser(stubApp)
+			q.AddCommand("run", "run it")
+			AddArg(q, "SRC", "source", new(string))
+		},
+		`command-with-args`:	func(p *OptsParser) { p.AddCommand("run", "run it") },
+	} {
+		func() {
+			defer func() {
+				if _, ok := recover().(OptsPanic); !ok {
+					t.Errorf("%q must panic with OptsPanic", testN)
+				}
+			}()
+
+			add(argsOpts(newTestParser(nil), &testArgsOpts{}))
+		}()
+	}
+}
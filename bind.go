@@ -0,0 +1,245 @@
+package optsparser
+
+import (
+	"encoding"
+	"flag"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// binder adds the option bound to the field of a struct, dflt is the value of the "default" tag, if set
+type binder func(p *OptsParser, optName, usage string, field reflect.Value, dflt *string) error
+
+// binders contains binders of all supported types of fields, except flag.Value and encoding.TextUnmarshaler
+var binders = map[reflect.Type]binder{
+	reflect.TypeOf(false):				bindScalar((*OptsParser).AddBool),
+	reflect.TypeOf(""):					bindScalar((*OptsParser).AddString),
+	reflect.TypeOf(int(0)):				bindScalar((*OptsParser).AddInt),
+	reflect.TypeOf(int64(0)):			bindScalar((*OptsParser).AddInt64),
+	reflect.TypeOf(uint(0)):			bindScalar((*OptsParser).AddUint),
+	reflect.TypeOf(uint64(0)):			bindScalar((*OptsParser).AddUint64),
+	reflect.TypeOf(float64(0)):			bindScalar((*OptsParser).AddFloat64),
+	reflect.TypeOf(time.Duration(0)):	bindScalar((*OptsParser).AddDuration),
+
+	reflect.TypeOf([]string{}):			bindSlice((*OptsParser).AddStrings),
+	reflect.TypeOf([]int{}):			bindSlice((*OptsParser).AddInts),
+	reflect.TypeOf([]int64{}):			bindSlice((*OptsParser).AddInt64s),
+	reflect.TypeOf([]uint{}):			bindSlice((*OptsParser).AddUints),
+	reflect.TypeOf([]uint64{}):			bindSlice((*OptsParser).AddUint64s),
+	reflect.TypeOf([]float64{}):		bindSlice((*OptsParser).AddFloat64s),
+	reflect.TypeOf([]time.Duration{}):	bindSlice((*OptsParser).AddDurations),
+
+	reflect.TypeOf(map[string]string{}):		bindMap[string](),
+	reflect.TypeOf(map[string]bool{}):			bindMap[bool](),
+	reflect.TypeOf(map[string]int{}):			bindMap[int](),
+	reflect.TypeOf(map[string]int64{}):			bindMap[int64](),
+	reflect.TypeOf(map[string]uint{}):			bindMap[uint](),
+	reflect.TypeOf(map[string]uint64{}):		bindMap[uint64](),
+	reflect.TypeOf(map[string]float64{}):		bindMap[float64](),
+	reflect.TypeOf(map[string]time.Duration{}):	bindMap[time.Duration](),
+}
+
+// Bind adds options bound to the fields of the struct pointed by ptr. Options are described by tags
+// of the fields:
+//
+//	opt       - name of the option in the usual format, e.g. "config-path|c"
+//	usage     - usage string of the option
+//	default   - default value of the option, if not set, the current value of the field is used
+//	required  - "true" makes the option required, like it was passed to [NewParser]
+//
+// For example:
+//
+//	type Config struct {
+//		Path    string        `opt:"config-path|c" usage:"path to configuration" required:"true"`
+//		Workers int           `opt:"workers|w" usage:"number of workers" default:"4"`
+//		Timeout time.Duration `opt:"timeout" usage:"request timeout" default:"30s"`
+//		DB      struct {
+//			Host string `opt:"host" usage:"database host" default:"localhost"`
+//		} `opt:"db"`
+//	}
+//
+// Fields of all types supported by Add* methods can be bound, i.e. bool, string, int, int64, uint,
+// uint64, float64 and [time.Duration], slices and maps with string keys of these types, and also
+// types implementing [flag.Value] or [encoding.TextUnmarshaler] by pointer. Default values of slices
+// and maps are separated by commas, e.g. "a,b,c" or "key1=v1,key2=v2". Fields of nested structs are
+// bound recursively, the "opt" tag of the nested struct is the prefix of long names of its options,
+// e.g. the option of the DB.Host field above is "--db-host". Fields without the "opt" tag, fields
+// with the "opt" tag set to "-" and unexported fields are ignored.
+//
+// Bind panics if ptr is not a pointer to a struct, the type of a tagged field is not supported,
+// or the default value is invalid.
+func (p *OptsParser) Bind(ptr any) {
+	v := reflect.ValueOf(ptr)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		doPanic("Bind requires a non-nil pointer to a struct, got %T", ptr)
+	}
+
+	p.bindStruct(v.Elem(), "")
+}
+
+func (p *OptsParser) bindStruct(v reflect.Value, prefix string) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		name, tagged := sf.Tag.Lookup("opt")
+		if !sf.IsExported() || name == "-" {
+			continue
+		}
+
+		field := v.Field(i)
+
+		// Is it a nested struct?
+		if field.Kind() == reflect.Struct && !isValueField(field) {
+			nested := prefix
+			if name != "" {
+				nested += name + "-"
+			}
+			p.bindStruct(field, nested)
+
+			continue
+		}
+
+		if !tagged {
+			continue
+		}
+
+		p.bindField(field, sf, prefixName(prefix, name))
+	}
+}
+
+func (p *OptsParser) bindField(field reflect.Value, sf reflect.StructField, optName string) {
+	usage := sf.Tag.Get("usage")
+
+	var dflt *string
+	if v, ok := sf.Tag.Lookup("default"); ok {
+		dflt = &v
+	}
+
+	var err error
+	switch val := field.Addr().Interface().(type) {
+	case flag.Value:
+		if dflt != nil {
+			err = val.Set(*dflt)
+		}
+		if err == nil {
+			p.AddVar(optName, usage, val)
+		}
+	case encoding.TextUnmarshaler:
+		if dflt != nil {
+			err = val.UnmarshalText([]byte(*dflt))
+		}
+		if err == nil {
+			p.AddTextVar(optName, usage, val, nil, "")
+		}
+	default:
+		bind, ok := binders[field.Type()]
+		if !ok {
+			doPanic("Unsupported type %s of field %s bound to option %q", field.Type(), sf.Name, optName)
+		}
+		err = bind(p, optName, usage, field, dflt)
+	}
+	if err != nil {
+		doPanic("Invalid default value of option %q: %v", optName, err)
+	}
+
+	// Is the option required?
+	if rq := sf.Tag.Get("required"); rq != "" {
+		required, err := strconv.ParseBool(rq)
+		if err != nil {
+			doPanic("Invalid value %q of the required tag of field %s", rq, sf.Name)
+		}
+		if required {
+			// Mark the option as required and already added
+			long, _, _ := strings.Cut(optName, "|")
+			p.required[long] = false
+		}
+	}
+}
+
+// prefixName adds the prefix to the long name of the option
+func prefixName(prefix, optName string) string {
+	if prefix == "" || len(optName) == 1 {
+		// No prefix or only short name
+		return optName
+	}
+
+	return prefix + optName
+}
+
+// isValueField returns true if the field is set by its own methods
+func isValueField(field reflect.Value) bool {
+	switch field.Addr().Interface().(type) {
+	case flag.Value, encoding.TextUnmarshaler:
+		return true
+	}
+
+	return false
+}
+
+func bindScalar[T Scalar](add func(*OptsParser, string, string, *T, T)) binder {
+	parse, _ := scalarParser[T]()
+
+	return func(p *OptsParser, optName, usage string, field reflect.Value, dflt *string) error {
+		val := field.Addr().Interface().(*T)	//nolint:forcetypeassert // type is checked by binders
+
+		dfltVal := *val
+		if dflt != nil {
+			var err error
+			if dfltVal, err = parse(*dflt); err != nil {
+				return err
+			}
+		}
+
+		add(p, optName, usage, val, dfltVal)
+
+		return nil
+	}
+}
+
+func bindSlice[T Scalar](add func(*OptsParser, string, string, *[]T, []T)) binder {
+	parse, _ := scalarParser[T]()
+
+	return func(p *OptsParser, optName, usage string, field reflect.Value, dflt *string) error {
+		val := field.Addr().Interface().(*[]T)	//nolint:forcetypeassert // type is checked by binders
+
+		dfltVal := *val
+		if dflt != nil {
+			dfltVal = []T{}
+			if *dflt != "" {
+				sv := &sliceValue[T]{val: &dfltVal, parse: parse, delim: ","}
+				if err := sv.Set(*dflt); err != nil {
+					return err
+				}
+			}
+		}
+
+		add(p, optName, usage, val, dfltVal)
+
+		return nil
+	}
+}
+
+func bindMap[T Scalar]() binder {
+	parse, _ := scalarParser[T]()
+
+	return func(p *OptsParser, optName, usage string, field reflect.Value, dflt *string) error {
+		val := field.Addr().Interface().(*map[string]T)	//nolint:forcetypeassert // type is checked by binders
+
+		dfltVal := *val
+		if dflt != nil {
+			dfltVal = map[string]T{}
+			if *dflt != "" {
+				mv := &mapValue[T]{val: &dfltVal, parse: parse, delim: mapDelimDefault}
+				if err := mv.Set(*dflt); err != nil {
+					return err
+				}
+			}
+		}
+
+		AddMap(p, optName, usage, val, dfltVal)
+
+		return nil
+	}
+}
@@ -0,0 +1,143 @@
+package optsparser
+
+import (
+	"bytes"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testLevel implements flag.Value
+type testLevel int
+
+func (l *testLevel) Set(s string) error {
+	*l = testLevel(len(s))
+	return nil
+}
+
+func (l *testLevel) String() string {
+	if l == nil {
+		return "0"
+	}
+	return strings.Repeat("*", int(*l))
+}
+
+type testBindDB struct {
+	Host	string			`opt:"host|H" usage:"database host" default:"localhost"`
+	Port	uint			`opt:"port" usage:"database port" default:"5432"`
+	Timeout	time.Duration	`opt:"timeout" usage:"connection timeout"`
+}
+
+type testBindOpts struct {
+	Config	string				`opt:"config-path|c" usage:"path to configuration" required:"true"`
+	Workers	int					`opt:"workers|w" usage:"number of workers" default:"4"`
+	Debug	bool				`opt:"debug" usage:"enable debug"`
+	Ratio	float64				`opt:"ratio" usage:"ratio" default:"0.5"`
+	Tags	[]string			`opt:"tag" usage:"tags" default:"a,b"`
+	Limits	map[string]int		`opt:"limit" usage:"resource limits" default:"cpu=2"`
+	Level	testLevel			`opt:"level" usage:"log level" default:"***"`
+	Addr	net.IP				`opt:"addr" usage:"listen address" default:"127.0.0.1"`
+	DB		testBindDB			`opt:"db"`
+	Inline	struct {
+		Name	string	`opt:"name" usage:"instance name" default:"main"`
+	}
+	Skipped		string	`opt:"-" usage:"skipped field"`
+	Untagged	string
+	private		string	`opt:"private"`
+}
+
+func TestBind(t *testing.T) {
+	t.Parallel()
+
+	to := testBindOpts{DB: testBindDB{Timeout: time.Second}}
+	p := newParser(stubApp).SetUsageOnFail(false).SetOutput(&bytes.Buffer{})
+	p.Bind(&to)
+
+	err := p.ParseArgs([]string{`-c`, `app.conf`, `--tag`, `x`, `--db-host`, `db.local`, `--level=**`,
+		`--addr`, `::1`, `--limit`, `mem=4`, `--name`, `backup`})
+	if err != nil {
+		t.Errorf("parse failed: %v", err)
+		t.FailNow()
+	}
+
+	want := testBindOpts{
+		Config:		`app.conf`,
+		Workers:	4,
+		Ratio:		0.5,
+		Tags:		[]string{`x`},
+		Limits:		map[string]int{`mem`: 4},
+		Level:		2,
+		Addr:		net.ParseIP(`::1`),
+		DB:			testBindDB{Host: `db.local`, Port: 5432, Timeout: time.Second},
+	}
+	want.Inline.Name = `backup`
+	if !reflect.DeepEqual(to, want) {
+		t.Errorf("incorrect Parse result: want - %#v got - %#v", want, to)
+	}
+
+	// Short name is not prefixed, ignored fields are not bound
+	if p.Lookup("H") == nil {
+		t.Errorf("short name of the nested option must not be prefixed")
+	}
+	for _, name := range []string{`Skipped`, `Untagged`, `private`, `-`} {
+		if p.Lookup(name) != nil {
+			t.Errorf("field %q must not be bound", name)
+		}
+	}
+
+	// Required option is checked
+	p = newParser(stubApp).SetUsageOnFail(false).SetOutput(&bytes.Buffer{})
+	p.Bind(&testBindOpts{})
+	if err := p.ParseArgs([]string{}); err == nil || err.Error() != `required option(s) is missing: --config-path` {
+		t.Errorf("unexpected error of missing required option: %v", err)
+	}
+}
+
+func TestBindUsage(t *testing.T) {
+	t.Parallel()
+
+	tOut := &bytes.Buffer{}
+	p := newParser(stubApp).SetOutput(tOut)
+	p.Bind(&testBindDB{Timeout: time.Minute})
+	p.Usage()
+
+	want := `
+Usage of ` + stubApp + `:
+    --host string, -H string
+      database host (default: localhost)
+    --port uint
+      database port (default: 5432)
+    --timeout duration
+      connection timeout (default: 1m0s)
+`
+	if tOut.String() != want {
+		t.Errorf("incorrect usage output: want -\n%s\ngot -\n%s", want, tOut.String())
+	}
+}
+
+func TestBindPanic(t *testing.T) {
+	t.Parallel()
+
+	for testN, bind := range map[string]func(p *OptsParser){
+		`not-pointer`:			func(p *OptsParser) { p.Bind(testBindDB{}) },
+		`not-struct`:			func(p *OptsParser) { p.Bind(new(int)) },
+		`unsupported-type`:		func(p *OptsParser) { p.Bind(&struct{ C chan int `opt:"c"` }{}) },
+		`invalid-default`:		func(p *OptsParser) { p.Bind(&struct{ N int `opt:"n" default:"x"` }{}) },
+		`invalid-list-default`:	func(p *OptsParser) { p.Bind(&struct{ N []int `opt:"n" default:"1,x"` }{}) },
+		`invalid-map-default`:	func(p *OptsParser) { p.Bind(&struct{ M map[string]string `opt:"m" default:"x"` }{}) },
+		`invalid-text-default`:	func(p *OptsParser) { p.Bind(&struct{ IP net.IP `opt:"ip" default:"x"` }{}) },
+		`invalid-required`:		func(p *OptsParser) { p.Bind(&struct{ S string `opt:"s" required:"yes"` }{}) },
+	} {
+		func() {
+			defer func() {
+				if _, ok := recover().(OptsPanic); !ok {
+					t.Errorf("%q must panic with OptsPanic", testN)
+				}
+			}()
+
+			bind(newParser(stubApp))
+		}()
+	}
+}
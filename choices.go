@@ -0,0 +1,105 @@
+package optsparser
+
+import (
+	"strings"
+)
+
+// choiceOption is implemented by values of options added by AddChoice* functions
+type choiceOption interface {
+	setChoiceDescr(choice, descr string) bool
+	setIgnoreCase(v bool)
+	choicesDescr() []string
+}
+
+// AddChoice adds a string option that accepts only one of the values from the choices list, with
+// specified option name, usage string and default value. The argument val points to a string variable
+// in which to store the value of the option. The list of choices is printed in the Usage output, each
+// of the choices can be described by [OptsParser.SetChoiceDescr]. By default, the values are matched
+// case-sensitively, use [OptsParser.SetIgnoreCase] to change it.
+//
+// AddChoice panics if the choices list is empty or the default value is not empty and is not
+// one of the choices.
+func (p *OptsParser) AddChoice(optName, usage string, val *string, dfltVal string, choices ...string) {
+	AddChoiceOf(p, optName, usage, val, dfltVal, choices...)
+}
+
+// AddChoiceOf works like [OptsParser.AddChoice], but for any type based on string,
+// which is useful for enumerations, e.g.:
+//  type Format string
+//  const (
+//      FormatJSON Format = "json"
+//      FormatYAML Format = "yaml"
+//  )
+//  var format Format
+//  optsparser.AddChoiceOf(p, "format|f", "output format", &format, FormatJSON, FormatJSON, FormatYAML)
+func AddChoiceOf[T ~string](p *OptsParser, optName, usage string, val *T, dfltVal T, choices ...T) {
+	if len(choices) == 0 {
+		doPanic("Option %q has no choices", optName)
+	}
+
+	cv := &choiceValue[T]{val: val, choices: choices, descrs: map[T]string{}}
+
+	// Check and set default value
+	if dfltVal != "" {
+		if err := cv.Set(string(dfltVal)); err != nil {
+			doPanic("Invalid default value of option %q: %v", optName, err)
+		}
+	} else {
+		*val = dfltVal
+	}
+
+	long, short, shOk := p.parseOptName(cv.list("|"), optName, usage)
+	p.Var(cv, long, usage)
+	if shOk {
+		p.Var(cv, short, usage)
+	}
+}
+
+// SetChoiceDescr sets the description of the choice of the option optName added by
+// [OptsParser.AddChoice] or [AddChoiceOf]. Descriptions are printed in the Usage output.
+// The optName can be either long or short name of the option. SetChoiceDescr panics if
+// the option was not added, it is not a choice option or it has no such choice.
+func (p *OptsParser) SetChoiceDescr(optName, choice, descr string) *OptsParser {
+	if !p.choiceOpt(optName).setChoiceDescr(choice, descr) {
+		doPanic("Option %q has no choice %q", optName, choice)
+	}
+
+	return p
+}
+
+// SetIgnoreCase enables case-insensitive matching of values of the option optName added by
+// [OptsParser.AddChoice] or [AddChoiceOf]. The variable of the option is always set to the
+// value as it was specified in the choices list. The optName can be either long or short
+// name of the option. SetIgnoreCase panics if the option was not added or it is not a choice option.
+func (p *OptsParser) SetIgnoreCase(optName string, v bool) *OptsParser {
+	p.choiceOpt(optName).setIgnoreCase(v)
+
+	return p
+}
+
+func (p *OptsParser) choiceOpt(optName string) choiceOption {
+	f := p.Lookup(optName)
+	if f == nil {
+		doPanic("Option %q was not added", optName)
+	}
+
+	co, ok := f.Value.(choiceOption)
+	if !ok {
+		doPanic("Option %q is not a choice option", optName)
+	}
+
+	return co
+}
+
+// descrChoices returns descriptions of choices, indented relative to the option description
+func (p *OptsParser) descrChoices(co choiceOption, col int) string {
+	indent := p.descrIndent(col) + "  "
+
+	out := &strings.Builder{}
+	for _, descr := range co.choicesDescr() {
+		// Continuation lines are aligned to the description of the choice
+		out.WriteString(wrapText(descr, indent, indent + "  ", p.width()))
+	}
+
+	return out.String()
+}
@@ -0,0 +1,115 @@
+package optsparser
+
+import (
+	"bytes"
+	"testing"
+)
+
+type testFormat string
+
+const (
+	testFormatJSON	testFormat = "json"
+	testFormatYAML	testFormat = "yaml"
+	testFormatText	testFormat = "text"
+)
+
+func TestChoices(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct{
+		args		[]string
+		ignoreCase	bool
+		wantMode	string
+		wantFormat	testFormat
+	}{
+		{ args: []string{},	wantMode: "fast", wantFormat: "" },
+		{ args: []string{`--mode`, `safe`, `-f`, `yaml`},	wantMode: "safe", wantFormat: testFormatYAML },
+		{ args: []string{`--format=TEXT`},	ignoreCase: true,	wantMode: "fast", wantFormat: testFormatText },
+	}
+
+	for i, test := range tests {
+		var mode string
+		var format testFormat
+
+		p := newParser(stubApp).SetUsageOnFail(false).SetOutput(&bytes.Buffer{})
+		p.AddChoice("mode", "mode of work", &mode, "fast", "fast", "safe")
+		AddChoiceOf(p, "format|f", "output format", &format, "", testFormatJSON, testFormatYAML, testFormatText)
+		p.SetIgnoreCase("f", test.ignoreCase)
+
+		if err := p.ParseArgs(test.args); err != nil {
+			t.Errorf("[%d] parse of %#v failed: %v", i, test.args, err)
+			continue
+		}
+		if mode != test.wantMode || format != test.wantFormat {
+			t.Errorf("[%d] incorrect Parse result of %#v: want - %q, %q got - %q, %q",
+				i, test.args, test.wantMode, test.wantFormat, mode, format)
+		}
+	}
+
+	// Invalid choices
+	for args, want := range map[string]string{
+		`--mode=slow`:		`invalid value "slow" for flag --mode: invalid choice "slow", must be one of: fast, safe`,
+		`--mode=FAST`:		`invalid value "FAST" for flag --mode: invalid choice "FAST", must be one of: fast, safe`,
+	} {
+		p := newParser(stubApp).SetUsageOnFail(false).SetOutput(&bytes.Buffer{})
+		p.AddChoice("mode", "mode of work", new(string), "fast", "fast", "safe")
+
+		if err := p.ParseArgs([]string{args}); err == nil || err.Error() != want {
+			t.Errorf("parse of %q returned unexpected error: want - %q got - %v", args, want, err)
+		}
+	}
+}
+
+func TestChoicesUsage(t *testing.T) {
+	t.Parallel()
+
+	tOut := &bytes.Buffer{}
+	p := newParser(stubApp, "format").SetOutput(tOut)
+	p.AddChoice("mode", "mode of work", new(string), "fast", "fast", "safe")
+	AddChoiceOf(p, "format|f", "output format", new(testFormat), "", testFormatJSON, testFormatYAML, testFormatText)
+	p.SetChoiceDescr("format", "json", "JSON document").
+		SetChoiceDescr("f", "text", "human readable text")
+	p.Usage()
+
+	want := `
+Usage of ` + stubApp + `:
+    --mode fast|safe
+      mode of work (default: fast)
+    --format json|yaml|text, -f json|yaml|text
+      output format (required option)
+        json - JSON document
+        text - human readable text
+`
+	if tOut.String() != want {
+		t.Errorf("output produced by Usage is different from expexted, see below:\n" +
+			"\n-------- Want --------\n%s\n" +
+			"-------- Got --------\n%s\n",
+			want, tOut.String(),
+		)
+	}
+}
+
+func TestChoicesPanic(t *testing.T) {
+	t.Parallel()
+
+	for i, f := range []func(p *OptsParser){
+		func(p *OptsParser) { p.AddChoice("no-choices", "", new(string), "") },
+		func(p *OptsParser) { p.AddChoice("invalid-default", "", new(string), "x", "a", "b") },
+		func(p *OptsParser) { p.SetChoiceDescr("mode", "slow", "slow mode") },
+		func(p *OptsParser) { p.SetChoiceDescr("unknown", "fast", "fast mode") },
+		func(p *OptsParser) { p.SetIgnoreCase("string-opt", true) },
+	} {
+		func() {
+			defer func() {
+				if _, ok := recover().(OptsPanic); !ok {
+					t.Errorf("[%d] case did not cause a panic, but it must", i)
+				}
+			}()
+
+			p := newParser(stubApp)
+			p.AddChoice("mode", "mode of work", new(string), "fast", "fast", "safe")
+			p.AddString("string-opt", "string value", new(string), "")
+			f(p)
+		}()
+	}
+}
@@ -0,0 +1,212 @@
+package optsparser
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// AddCommand adds a subcommand with the name, the description and the set of required options
+// and returns the parser of the command. The command parser works like a parser created by
+// [NewParser]: it has its own options, required options, general description and separators.
+// Options of the parent are global for the command, i.e. they can be specified both before and
+// after the command name, e.g. "tool --verbose db migrate --dry-run --verbose".
+//
+// The command parser inherits settings of the parent (output, usage on fail, report all mode, strict
+// dashes, interspersed mode, negation prefix, environment prefix, response files, exit function and
+// format of the Usage output) at the moment of creation, so configure the parent before adding commands.
+// The first positional argument of a parser with commands is always the name of the command,
+// the remaining arguments are parsed by the command parser. Use [OptsParser.SetRun] to set
+// the function to run the command, and [OptsParser.Run] to parse arguments and run the
+// selected command.
+//
+// AddCommand panics if the name is empty, the command with the same name was already added or
+// the parser has declared positional arguments.
+func (p *OptsParser) AddCommand(name, descr string, required ...string) *OptsParser {
+	if name == "" || strings.HasPrefix(name, "-") {
+		doPanic("Invalid command name %q", name)
+	}
+	if _, ok := p.commands[name]; ok {
+		doPanic("Command %q is already added", name)
+	}
+	if len(p.args) != 0 {
+		doPanic("Cannot add command %q - parser has declared arguments", name)
+	}
+
+	cmdName := name
+	if p.Name() != "" {
+		cmdName = p.Name() + " " + name
+	}
+
+	cmd := NewParser(cmdName, required...)
+	cmd.parent = p
+	cmd.cmdDescr = descr
+	cmd.inherit(p)
+
+	if p.commands == nil {
+		p.commands = map[string]*OptsParser{}
+	}
+	p.commands[name] = cmd
+	p.cmdOrder = append(p.cmdOrder, name)
+
+	return cmd
+}
+
+// SetRun sets the function to run the command, the function gets the parser of the command.
+// If the parser has commands, but the command name is not specified in the arguments, the run
+// function of the parser itself is called, or the parsing fails if the function is not set.
+func (p *OptsParser) SetRun(run func(cmd *OptsParser) error) *OptsParser {
+	p.run = run
+
+	return p
+}
+
+// Run parses command-line options from os.Args[1:] like [OptsParser.Parse] does, then calls
+// the run function of the selected command, see [OptsParser.SetRun]. It returns the parsing
+// error or the error returned by the run function.
+func (p *OptsParser) Run() error {
+	return p.RunArgs(os.Args[1:])
+}
+
+// RunArgs works like [OptsParser.Run], but parses the options from the args list instead of os.Args.
+func (p *OptsParser) RunArgs(args []string) error {
+	if err := p.ParseArgs(args); err != nil {
+		return err
+	}
+
+	cmd := p.Command()
+	if cmd.run == nil {
+		// Nothing to run
+		return nil
+	}
+
+	return cmd.run(cmd)
+}
+
+// Command returns the parser of the command selected by the last parsing. If no command was
+// selected, the parser itself is returned.
+func (p *OptsParser) Command() *OptsParser {
+	cmd := p
+	for cmd.selected != nil {
+		cmd = cmd.selected
+	}
+
+	return cmd
+}
+
+// Parent returns the parser of the parent command, or nil if the parser is not a command.
+func (p *OptsParser) Parent() *OptsParser {
+	return p.parent
+}
+
+// inherit copies settings of the parent parser
+func (p *OptsParser) inherit(parent *OptsParser) {
+	p.FlagSet.SetOutput(parent.Output())
+	p.outputSet = parent.outputSet
+	p.usageOnFail = parent.usageOnFail
+	p.reportAll = parent.reportAll
+	p.strictDashes = parent.strictDashes
+	p.interspersed = parent.interspersed
+	p.negPrefix = parent.negPrefix
+	p.envPrefix = parent.envPrefix
+	p.noRespFiles = parent.noRespFiles
+	p.respFilePrefix = parent.respFilePrefix
+	p.lsJoinStr = parent.lsJoinStr
+	p.shortFirst = parent.shortFirst
+	p.autoSynopsis = parent.autoSynopsis
+	p.usageWidth = parent.usageWidth
+	p.optIndent = parent.optIndent
+	p.helpIndent = parent.helpIndent
+	p.twoColumns = parent.twoColumns
+	p.lookupEnv = parent.lookupEnv
+	p.exitFunc = parent.exitFunc
+}
+
+// dispatch selects the command by the first positional argument and passes the rest of arguments
+// to it. It returns nil command if the parser itself is selected.
+func (p *OptsParser) dispatch(origins []argOrigin) (*OptsParser, error) {
+	p.selected = nil
+
+	if len(p.commands) == 0 {
+		return nil, nil
+	}
+
+	if p.NArg() == 0 {
+		if p.run != nil {
+			// The parser itself can be run
+			return nil, nil
+		}
+		return nil, fmt.Errorf("command is required, one of: %s", strings.Join(p.cmdOrder, ", "))
+	}
+
+	cmd, ok := p.commands[p.Arg(0)]
+	if !ok {
+		return nil, fmt.Errorf("unknown command %q", p.Arg(0))
+	}
+	p.selected = cmd
+
+	// Arguments after the command name are already expanded, keep their origins
+	return cmd, cmd.parse(p.Args()[1:], origins[1:])
+}
+
+// cmdAssigns returns option assignments from arguments of the command selected by the first positional
+// argument and from arguments of its subcommands. Errors are ignored, they are reported by commands.
+func (p *OptsParser) cmdAssigns(positional []string) []optAssign {
+	if len(positional) == 0 {
+		return nil
+	}
+
+	cmd, ok := p.commands[positional[0]]
+	if !ok {
+		return nil
+	}
+
+	assigns, rest, _ := cmd.tokenize(positional[1:], nil)
+
+	return append(assigns, cmd.cmdAssigns(rest)...)
+}
+
+// chain returns the list of parsers from the root parser to this parser
+func (p *OptsParser) chain() []*OptsParser {
+	if p.parent == nil {
+		return []*OptsParser{p}
+	}
+
+	return append(p.parent.chain(), p)
+}
+
+// explainRequested returns true if the explanation of values was requested for this parser or any parent
+func (p *OptsParser) explainRequested() bool {
+	for q := p; q != nil; q = q.parent {
+		if q.explain {
+			return true
+		}
+	}
+
+	return false
+}
+
+// descrCommands writes the list of commands and options of parent commands
+func (p *OptsParser) descrCommands(out io.Writer) {
+	if len(p.cmdOrder) != 0 {
+		fmt.Fprintf(out, "\nCommands:\n")
+		col := p.column(p.cmdOrder)
+		for _, name := range p.cmdOrder {
+			if descr := p.commands[name].cmdDescr; descr != "" {
+				fmt.Fprint(out, p.layoutEntry(name, descr, col))
+			} else {
+				fmt.Fprintf(out, p.optIndent + "%s\n", name)
+			}
+		}
+	}
+
+	if p.parent == nil {
+		return
+	}
+
+	fmt.Fprintf(out, "\nGlobal options:\n")
+	for _, q := range p.parent.chain() {
+		q.descrOptions(out)
+	}
+}
@@ -0,0 +1,278 @@
+package optsparser
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type testCmdOpts struct {
+	verbose	bool
+	config	string
+	dsn		string
+	dryRun	bool
+	steps	int
+	addr	string
+	ran		string
+}
+
+func newCmdParser(to *testCmdOpts, tOut *bytes.Buffer) *OptsParser {
+	p := newParser(stubApp).SetUsageOnFail(false).SetOutput(tOut)
+	p.AddBool("verbose|v", "verbose output", &to.verbose, false)
+	p.AddString("config|c", "path to configuration", &to.config, "")
+
+	run := func(cmd *OptsParser) error {
+		to.ran = cmd.Name()
+		return nil
+	}
+
+	db := p.AddCommand("db", "database management", "dsn")
+	db.AddString("dsn", "database connection string", &to.dsn, "")
+
+	migrate := db.AddCommand("migrate", "apply migrations").SetRun(run)
+	migrate.AddBool("dry-run|n", "do not apply changes", &to.dryRun, false)
+	migrate.AddInt("steps", "number of migrations to apply", &to.steps, 0)
+
+	serve := p.AddCommand("serve", "start the server").SetRun(run)
+	serve.AddString("addr|a", "listen address", &to.addr, ":8080")
+
+	return p
+}
+
+func TestCommands(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct{
+		args	[]string
+		want	testCmdOpts
+		posArgs	[]string
+	}{
+		`nested-command`:	{
+			args:		[]string{`-v`, `db`, `--dsn`, `pg://`, `migrate`, `-n`, `--steps=2`, `extra`},
+			want:		testCmdOpts{verbose: true, dsn: `pg://`, dryRun: true, steps: 2, addr: `:8080`,
+							ran: stubApp + ` db migrate`},
+			posArgs:	[]string{`extra`},
+		},
+		`global-options-after-command`:	{
+			args:		[]string{`db`, `migrate`, `-vn`, `--dsn`, `pg://`, `-c`, `app.conf`},
+			want:		testCmdOpts{verbose: true, config: `app.conf`, dsn: `pg://`, dryRun: true,
+							addr: `:8080`, ran: stubApp + ` db migrate`},
+			posArgs:	[]string{},
+		},
+		`another-command`:	{
+			args:		[]string{`serve`, `-a`, `:9090`, `--`, `-v`},
+			want:		testCmdOpts{addr: `:9090`, ran: stubApp + ` serve`},
+			posArgs:	[]string{`-v`},
+		},
+	}
+
+	for testN, test := range tests {
+		testN, test := testN, test
+		t.Run(testN, func(t *testing.T) {
+			t.Parallel()
+
+			to := testCmdOpts{}
+			p := newCmdParser(&to, &bytes.Buffer{})
+
+			if err := p.RunArgs(test.args); err != nil {
+				t.Errorf("%q run failed: %v", testN, err)
+				return
+			}
+
+			if to != test.want {
+				t.Errorf("%q incorrect Run result: want - %#v got - %#v", testN, test.want, to)
+			}
+			if args := p.Command().Args(); !reflect.DeepEqual(args, test.posArgs) {
+				t.Errorf("%q incorrect arguments: want - %#v got - %#v", testN, test.posArgs, args)
+			}
+		})
+	}
+}
+
+func TestCommandsFail(t *testing.T) {
+	t.Parallel()
+
+	for want, args := range map[string][]string{
+		`command is required, one of: db, serve`:				{`-v`},
+		`unknown command "deploy"`:								{`deploy`},
+		`command is required, one of: migrate`:					{`db`, `--dsn`, `pg://`},
+		`required option(s) is missing: --dsn`:					{`db`, `migrate`},
+		`flag provided but not defined: --addr`:				{`db`, `migrate`, `--addr`, `:80`},
+		`invalid value "x" for flag --steps: parse error`:		{`db`, `--dsn`, `pg://`, `migrate`, `--steps`, `x`},
+	} {
+		to := testCmdOpts{}
+		p := newCmdParser(&to, &bytes.Buffer{})
+
+		err := p.RunArgs(args)
+		if err == nil {
+			t.Errorf("run of %#v must fail but it succeeds", args)
+			continue
+		}
+		if err.Error() != want {
+			t.Errorf("run of %#v returned unexpected error: want - %q got - %q", args, want, err.Error())
+		}
+		if to.ran != "" {
+			t.Errorf("run of %#v must not run command %q", args, to.ran)
+		}
+	}
+
+	// Error returned by the run function
+	errRun := errors.New("run failed")
+	p := newParser(stubApp).SetUsageOnFail(false).SetOutput(&bytes.Buffer{})
+	p.AddCommand("fail", "always fails").SetRun(func(*OptsParser) error { return errRun })
+	if err := p.RunArgs([]string{`fail`}); !errors.Is(err, errRun) {
+		t.Errorf("unexpected error of run function: want - %v got - %v", errRun, err)
+	}
+
+	// All errors of the command are reported in the report all mode of the parent
+	p = newParser(stubApp).SetUsageOnFail(false).SetOutput(&bytes.Buffer{}).SetReportAll(true)
+	db := p.AddCommand("db", "database management", "dsn")
+	db.AddString("dsn", "database connection string", new(string), "")
+	db.AddInt("jobs|n", "number of jobs", new(int), 1)
+
+	var errs ParseErrors
+	if err := p.ParseArgs([]string{`db`, `--bogus`, `-n`, `x`}); !errors.As(err, &errs) {
+		t.Errorf("unexpected error type: want - %T got - %T (%v)", errs, err, err)
+		t.FailNow()
+	}
+	want := []string{
+		`flag provided but not defined: --bogus`,
+		`invalid value "x" for flag -n: parse error`,
+		`required option(s) is missing: --dsn`,
+	}
+	got := make([]string, 0, len(errs))
+	for _, e := range errs {
+		got = append(got, e.Error())
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("incorrect list of errors:\nwant - %#v\ngot  - %#v", want, got)
+	}
+}
+
+func TestCommandsSelf(t *testing.T) {
+	t.Parallel()
+
+	to := testCmdOpts{}
+	p := newCmdParser(&to, &bytes.Buffer{}).SetRun(func(cmd *OptsParser) error {
+		to.ran = cmd.Name()
+		return nil
+	})
+
+	if err := p.RunArgs([]string{`-v`}); err != nil {
+		t.Errorf("run failed: %v", err)
+		t.FailNow()
+	}
+	if to.ran != stubApp || p.Command() != p {
+		t.Errorf("parser itself must be run, but %q was run", to.ran)
+	}
+	if p.Parent() != nil || p.Command().Parent() != nil {
+		t.Errorf("root parser must not have a parent")
+	}
+}
+
+func TestCommandsSources(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	rsp := writeRespFile(t, dir, "args.rsp", "--dsn pg://\nmigrate\n")
+
+	p := newCmdParser(&testCmdOpts{}, &bytes.Buffer{})
+	if err := p.ParseArgs([]string{`db`, `@` + rsp, `-n`}); err != nil {
+		t.Errorf("parse failed: %v", err)
+		t.FailNow()
+	}
+
+	db := p.Command().Parent()
+	if got, want := db.Source("dsn"), (Source{Kind: SourceArgs, ArgIndex: 1, File: rsp, Line: 1}); got != want {
+		t.Errorf("incorrect source of %q: want - %#v got - %#v", "dsn", want, got)
+	}
+	if got, want := p.Command().Source("n"), (Source{Kind: SourceArgs, ArgIndex: 2}); got != want {
+		t.Errorf("incorrect source of %q: want - %#v got - %#v", "n", want, got)
+	}
+}
+
+func TestCommandsConfig(t *testing.T) {
+	t.Parallel()
+
+	cfg := writeConfig(t, "app.conf", "verbose = true\nlevel = 3\n")
+
+	for testN, test := range map[string]struct{
+		args	[]string
+		verbose	bool
+		level	int
+	}{
+		`before-command`:		{args: []string{`--config`, cfg, `serve`}, verbose: true, level: 3},
+		`after-command`:		{args: []string{`serve`, `--config`, cfg}, verbose: true, level: 3},
+		`args-override-file`:	{args: []string{`--verbose=false`, `serve`, `--level=5`, `-C`, cfg}, level: 5},
+	} {
+		verbose, level := false, 0
+		p := newParser(stubApp).SetUsageOnFail(false).SetOutput(&bytes.Buffer{})
+		p.AddConfig("config|C", "path to configuration", "")
+		p.AddBool("verbose|v", "verbose output", &verbose, false)
+		p.AddInt("level", "level of details", &level, 1)
+		p.AddCommand("serve", "start the server")
+
+		if err := p.ParseArgs(test.args); err != nil {
+			t.Errorf("%q parse failed: %v", testN, err)
+			continue
+		}
+		if verbose != test.verbose || level != test.level {
+			t.Errorf("%q incorrect values: want - %t, %d got - %t, %d", testN, test.verbose, test.level, verbose, level)
+		}
+	}
+}
+
+func TestCommandsUsage(t *testing.T) {
+	t.Parallel()
+
+	tOut := &bytes.Buffer{}
+	p := newCmdParser(&testCmdOpts{}, tOut).SetUsageOnFail(true)
+
+	if err := p.ParseArgs([]string{`db`, `--dsn`, `pg://`, `migrate`, `--help`}); err == nil {
+		t.Errorf("parse must fail but it succeeds")
+	}
+
+	want := `
+Usage of ` + stubApp + ` db migrate:
+    --dry-run[=true|false], -n[=true|false]
+      do not apply changes (default: false)
+    --steps int
+      number of migrations to apply (default: 0)
+
+Global options:
+    --verbose[=true|false], -v[=true|false]
+      verbose output (default: false)
+    --config string, -c string
+      path to configuration (default: "")
+    --dsn string
+      database connection string (required option)
+`
+	if got := tOut.String(); !strings.HasPrefix(got, want) {
+		t.Errorf("incorrect usage output: want -\n%s\ngot -\n%s", want, got)
+	}
+
+	tOut.Reset()
+	p = newCmdParser(&testCmdOpts{}, tOut).SetUsageOnFail(true)
+	_ = p.ParseArgs([]string{`deploy`})
+
+	want = `
+Usage ERROR: unknown command "deploy"
+
+Usage of ` + stubApp + `:
+    --verbose[=true|false], -v[=true|false]
+      verbose output (default: false)
+    --config string, -c string
+      path to configuration (default: "")
+
+Commands:
+    db
+      database management
+    serve
+      start the server
+`
+	if got := tOut.String(); got != want {
+		t.Errorf("incorrect usage output: want -\n%s\ngot -\n%s", want, got)
+	}
+}
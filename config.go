@@ -0,0 +1,310 @@
+package optsparser
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// cfgValue is the value of an option read from the configuration file
+type cfgValue struct {
+	name	string
+	value	string
+	line	int
+	noValue	bool	// option is specified without value
+}
+
+// AddConfig adds a string option with specified option name, usage string and default value, which
+// sets the path to the configuration file. Values of options are loaded from the configuration file
+// after setting defaults, but before applying the environment and the command line, i.e. the file
+// values override defaults, but the environment variables and the command line override file values.
+// Required options can be satisfied by the configuration file.
+//
+// Two formats of the configuration file are supported. If the file name has the ".json" extension or
+// the file content starts with "{", the file is treated as a JSON object, where keys are names of options.
+// Arrays set multiple values of an option, objects set key=value pairs of map options. Otherwise, the file
+// consists of lines in the form "name = value", empty lines and lines started with "#" or ";" are ignored.
+// The value can be quoted by double quotes using Go syntax. The name of a boolean option without value
+// sets the option to true. In both formats options can be referenced by long or short names without dashes,
+// the multi-value options can be repeated.
+//
+// It is not an error if the file does not exist and its path was not set explicitly,
+// by the command line or by the environment.
+func (p *OptsParser) AddConfig(optName, usage string, dfltVal string) {
+	if p.configOpt != "" {
+		doPanic("Configuration option is already added: %q", p.configOpt)
+	}
+
+	long, short, shOk := p.parseOptName(typeString, optName, usage)
+	p.String(long, dfltVal, usage)
+	if shOk {
+		// Use the same value for both forms of the option
+		p.Var(p.Lookup(long).Value, short, usage)
+	}
+
+	p.configOpt = long
+}
+
+// configPath returns the path to the configuration file and whether it was set explicitly
+func (p *OptsParser) configPath(assigns []optAssign) (string, bool) {
+	// Default value
+	path, explicit := p.Lookup(p.configOpt).Value.String(), false
+
+	// Value from the environment
+	if env := p.envName(p.configOpt); env != "" {
+		if v, ok := p.lookupEnv(env); ok {
+			path, explicit = v, true
+		}
+	}
+
+	// Value from the command line, commands can have their own options with the same name
+	for _, opt := range assigns {
+		if opt.owner == p && p.longName(opt.name) == p.configOpt {
+			path, explicit = opt.value, true
+		}
+	}
+
+	return path, explicit
+}
+
+// applyConfig sets values of options from the configuration file
+func (p *OptsParser) applyConfig(assigns []optAssign) error {
+	if p.configOpt == "" {
+		// Configuration file is not supported
+		return nil
+	}
+
+	path, explicit := p.configPath(assigns)
+	if path == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !explicit && errors.Is(err, fs.ErrNotExist) {
+			// Default configuration does not exist, it is not an error
+			return nil
+		}
+		return fmt.Errorf("cannot read configuration: %w", err)
+	}
+
+	var values []cfgValue
+	if strings.EqualFold(filepath.Ext(path), ".json") || bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		values, err = parseJSONConfig(data)
+	} else {
+		values, err = parseTextConfig(data)
+	}
+	if err != nil {
+		return fmt.Errorf("%s:%w", path, err)
+	}
+
+	for _, v := range values {
+		if err := p.setFromConfig(path, v); err != nil {
+			if err := p.report(fmt.Errorf("%s:%d: %w", path, v.line, err)); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Values from the next source have to replace values from the configuration
+	p.newSource()
+
+	return nil
+}
+
+func (p *OptsParser) setFromConfig(path string, v cfgValue) error {
+	f := p.Lookup(v.name)
+	if f == nil || strings.HasPrefix(v.name, sepPrefix) {
+		return &UnknownOptionError{Option: v.name}
+	}
+
+	if p.longName(v.name) == p.configOpt {
+		return fmt.Errorf("option %q cannot be set by the configuration file", v.name)
+	}
+
+	value := v.value
+	if v.noValue {
+		// Option without value, it is allowed only for boolean options
+		if !isBoolFlag(f) {
+			return fmt.Errorf("option %q requires a value", v.name)
+		}
+		value = "true"
+	}
+
+	src := Source{Kind: SourceFile, File: path, Line: v.line}
+	if err := p.setOpt(v.name, value, src); err != nil {
+		return &InvalidValueError{Option: v.name, Value: value, Cause: err, Source: src}
+	}
+
+	return nil
+}
+
+// longName returns the long name of the option, if name is short, or the name itself
+func (p *OptsParser) longName(name string) string {
+	if long, ok := p.shToLong[name]; ok {
+		return long
+	}
+
+	return name
+}
+
+// parseTextConfig parses the configuration in the "name = value" format
+func parseTextConfig(data []byte) ([]cfgValue, error) {
+	values := []cfgValue{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	n := 0
+	for scanner.Scan() {
+		n++
+		line := strings.TrimSpace(scanner.Text())
+
+		// Skip empty lines and comments
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+
+		name, value, hasValue := strings.Cut(line, "=")
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		if name == "" {
+			return nil, fmt.Errorf("%d: option name is empty", n)
+		}
+
+		// Is the value quoted?
+		if strings.HasPrefix(value, `"`) {
+			var err error
+			if value, err = strconv.Unquote(value); err != nil {
+				return nil, fmt.Errorf("%d: invalid quoted value of option %q", n, name)
+			}
+		}
+
+		values = append(values, cfgValue{name: name, value: value, line: n, noValue: !hasValue})
+	}
+
+	if err := scanner.Err(); err != nil {
+		// The line following the last successfully read line is failed
+		return nil, fmt.Errorf("%d: %w", n + 1, err)
+	}
+
+	return values, nil
+}
+
+// parseJSONConfig parses the configuration in JSON format
+func parseJSONConfig(data []byte) ([]cfgValue, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	// Function to produce error with the line number
+	lineErr := func(err error) error {
+		offset := dec.InputOffset()
+		var se *json.SyntaxError
+		if errors.As(err, &se) {
+			offset = se.Offset
+		}
+		return fmt.Errorf("%d: %w", lineAt(data, offset), err)
+	}
+
+	if tok, err := dec.Token(); err != nil {
+		return nil, lineErr(err)
+	} else if tok != json.Delim('{') {
+		return nil, lineErr(errors.New("configuration must be a JSON object"))
+	}
+
+	values := []cfgValue{}
+	for dec.More() {
+		// Read name of the option
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, lineErr(err)
+		}
+		name, _ := tok.(string)
+		line := lineAt(data, dec.InputOffset())
+
+		// Read value of the option
+		var raw any
+		if err := dec.Decode(&raw); err != nil {
+			return nil, lineErr(err)
+		}
+
+		items, err := jsonValues(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%d: invalid value of option %q: %w", line, name, err)
+		}
+
+		for _, item := range items {
+			values = append(values, cfgValue{name: name, value: item, line: line})
+		}
+	}
+
+	if _, err := dec.Token(); err != nil {
+		return nil, lineErr(err)
+	}
+
+	return values, nil
+}
+
+// jsonValues converts the JSON value to the list of values of an option
+func jsonValues(raw any) ([]string, error) {
+	switch v := raw.(type) {
+	case nil:
+		return []string{}, nil
+	case []any:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			value, err := jsonScalar(item)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+		return values, nil
+	case map[string]any:
+		values := make([]string, 0, len(v))
+		for key, item := range v {
+			value, err := jsonScalar(item)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, key + "=" + value)
+		}
+		// Keep the order of pairs stable
+		sort.Strings(values)
+		return values, nil
+	}
+
+	value, err := jsonScalar(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	return []string{value}, nil
+}
+
+func jsonScalar(raw any) (string, error) {
+	switch v := raw.(type) {
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	}
+
+	return "", fmt.Errorf("unsupported value %v", raw)
+}
+
+// lineAt returns the number of line which contains the offset
+func lineAt(data []byte, offset int64) int {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+
+	return bytes.Count(data[:offset], []byte("\n")) + 1
+}
@@ -0,0 +1,227 @@
+package optsparser
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+type testConfigOpts struct {
+	name	string
+	workers	int
+	debug	bool
+	tags	[]string
+	labels	map[string]string
+}
+
+// configOpts adds options used by tests of configuration files
+func configOpts(p *OptsParser, to *testConfigOpts) *OptsParser {
+	p.AddConfig("config|c", "path to configuration", "")
+	p.AddString("name|n", "name of instance", &to.name, "default")
+	p.AddInt("workers|w", "number of workers", &to.workers, 1)
+	p.AddBool("debug|d", "debug mode", &to.debug, false)
+	p.AddStrings("tags", "list of tags", &to.tags, []string{"default"})
+	p.AddStringMap("label", "labels", &to.labels, nil)
+
+	return p
+}
+
+func writeConfig(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("cannot write configuration: %v", err)
+	}
+
+	return path
+}
+
+const testTextConfig = `
+# Test configuration
+name = "from file"
+workers=4
+; boolean option without value
+d
+tags = a
+tags = b
+label = env=prod,team=core
+`
+
+const testJSONConfig = `{
+	"name":		"from file",
+	"w":		4,
+	"debug":	true,
+	"tags":		["a", "b"],
+	"label":	{"env": "prod", "team": "core"},
+	"unset":	null
+}`
+
+func TestConfig(t *testing.T) {
+	t.Parallel()
+
+	textCfg := writeConfig(t, "app.conf", testTextConfig)
+	jsonCfg := writeConfig(t, "app.json", testJSONConfig)
+
+	fileOpts := testConfigOpts{
+		name:		"from file",
+		workers:	4,
+		debug:		true,
+		tags:		[]string{"a", "b"},
+		labels:		map[string]string{"env": "prod", "team": "core"},
+	}
+
+	tests := map[string]struct{
+		args	[]string
+		env		map[string]string
+		want	testConfigOpts
+	}{
+		`no-config`:	{
+			args:	[]string{},
+			want:	testConfigOpts{name: "default", workers: 1, tags: []string{"default"}, labels: map[string]string{}},
+		},
+		`text-config`:	{ args: []string{`-c`, textCfg}, want: fileOpts },
+		`json-config`:	{ args: []string{`--config=` + jsonCfg}, want: fileOpts },
+		`config-from-env`:	{ args: []string{}, env: map[string]string{`APP_CONFIG`: jsonCfg}, want: fileOpts },
+		`args-and-env-override`:	{
+			args:	[]string{`-c`, textCfg, `--tags`, `c`, `-w`, `8`},
+			env:	map[string]string{`APP_NAME`: `from env`},
+			want:	testConfigOpts{
+				name:		"from env",
+				workers:	8,
+				debug:		true,
+				tags:		[]string{"c"},
+				labels:		map[string]string{"env": "prod", "team": "core"},
+			},
+		},
+	}
+
+	for testN, test := range tests {
+		testN, test := testN, test
+		t.Run(testN, func(t *testing.T) {
+			t.Parallel()
+
+			to := testConfigOpts{}
+			p := configOpts(newTestParser(test.env), &to)
+
+			if err := p.ParseArgs(test.args); err != nil {
+				t.Errorf("%q parse failed: %v", testN, err)
+				return
+			}
+
+			if !reflect.DeepEqual(to, test.want) {
+				t.Errorf("%q incorrect Parse result: want - %#v got - %#v", testN, test.want, to)
+			}
+		})
+	}
+}
+
+func TestConfigRequired(t *testing.T) {
+	t.Parallel()
+
+	cfg := writeConfig(t, "app.conf", "name = test\n")
+
+	p := configOpts(newTestParser(nil, "name"), &testConfigOpts{})
+	if err := p.ParseArgs([]string{`-c`, cfg}); err != nil {
+		t.Errorf("parse failed: %v", err)
+	}
+}
+
+func TestConfigDefaultMissing(t *testing.T) {
+	t.Parallel()
+
+	missing := filepath.Join(t.TempDir(), "missing.conf")
+
+	// Missing default configuration is not an error
+	p := newParser(stubApp).SetUsageOnFail(false).SetOutput(&bytes.Buffer{})
+	p.AddConfig("config", "path to configuration", missing)
+	if err := p.ParseArgs([]string{}); err != nil {
+		t.Errorf("parse failed: %v", err)
+	}
+
+	// Missing explicitly specified configuration is an error
+	p = newParser(stubApp).SetUsageOnFail(false).SetOutput(&bytes.Buffer{})
+	p.AddConfig("config", "path to configuration", "")
+	if err := p.ParseArgs([]string{`--config`, missing}); err == nil {
+		t.Errorf("parse with missing configuration must fail but it succeeds")
+	}
+}
+
+func TestConfigFail(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct{
+		file	string
+		content	string
+		want	string
+	}{
+		`unknown-option`:	{
+			file:		"app.conf",
+			content:	"name = x\n\nunknown = 1\n",
+			want:		`app.conf:3: unknown option "unknown"`,
+		},
+		`invalid-value`:	{
+			file:		"app.conf",
+			content:	"# comment\nworkers = many\n",
+			want:		`app.conf:2: invalid value "many" for option "workers": parse error`,
+		},
+		`missing-value`:	{
+			file:		"app.conf",
+			content:	"name\n",
+			want:		`app.conf:1: option "name" requires a value`,
+		},
+		`empty-name`:	{
+			file:		"app.conf",
+			content:	"\n = x\n",
+			want:		`app.conf:2: option name is empty`,
+		},
+		`invalid-quotes`:	{
+			file:		"app.conf",
+			content:	`name = "x`,
+			want:		`app.conf:1: invalid quoted value of option "name"`,
+		},
+		`config-in-config`:	{
+			file:		"app.conf",
+			content:	"config = other.conf\n",
+			want:		`app.conf:1: option "config" cannot be set by the configuration file`,
+		},
+		`json-invalid-value`:	{
+			file:		"app.json",
+			content:	"{\n\t\"name\": \"x\",\n\t\"workers\": \"many\"\n}",
+			want:		`app.json:3: invalid value "many" for option "workers": parse error`,
+		},
+		`json-syntax`:	{
+			file:		"app.json",
+			content:	"{\n\t\"name\": \"x\",\n\t\"workers\" 1\n}",
+			want:		`app.json:3: invalid character '1' after object key`,
+		},
+		`json-not-object`:	{
+			file:		"app.json",
+			content:	`["name"]`,
+			want:		`app.json:1: configuration must be a JSON object`,
+		},
+		`json-nested`:	{
+			file:		"app.json",
+			content:	"{\n\t\"tags\": [[\"a\"]]\n}",
+			want:		`app.json:2: invalid value of option "tags": unsupported value [a]`,
+		},
+	}
+
+	for testN, test := range tests {
+		testN, test := testN, test
+		t.Run(testN, func(t *testing.T) {
+			t.Parallel()
+
+			path := writeConfig(t, test.file, test.content)
+			p := configOpts(newTestParser(nil), &testConfigOpts{})
+
+			err := p.ParseArgs([]string{`-c`, path})
+			want := filepath.Dir(path) + string(filepath.Separator) + test.want
+			if err == nil || err.Error() != want {
+				t.Errorf("%q parse returned unexpected error: want - %q got - %v", testN, want, err)
+			}
+		})
+	}
+}
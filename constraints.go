@@ -0,0 +1,267 @@
+package optsparser
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ConstraintKind is the kind of relation between options
+type ConstraintKind int
+
+const (
+	// ConstraintRequires means that the option requires other options, see [OptsParser.SetRequires]
+	ConstraintRequires ConstraintKind = iota
+	// ConstraintRequiredIf means that the option is required if another option is set,
+	// see [OptsParser.SetRequiredIf]
+	ConstraintRequiredIf
+	// ConstraintImplies means that the option sets values of other options, see [OptsParser.SetImplies]
+	ConstraintImplies
+	// ConstraintAtLeastOne means that at least one of options is required, see [OptsParser.SetAtLeastOne]
+	ConstraintAtLeastOne
+)
+
+// constraint describes the relation between options, all names are long
+type constraint struct {
+	kind	ConstraintKind
+	option	string		// option which the constraint belongs to
+	options	[]string	// related options
+	values	[]string	// values of the option for ConstraintRequiredIf, values of related options for ConstraintImplies
+}
+
+// ConstraintError is returned by the parser if a relation between options is violated
+type ConstraintError struct {
+	Kind	ConstraintKind
+	Option	OptionName		// option which the constraint belongs to, empty for ConstraintAtLeastOne
+	Missing	[]OptionName	// options that have to be set
+	values	[]string		// values of the option for ConstraintRequiredIf
+}
+
+func (e *ConstraintError) Error() string {
+	missing := make([]string, 0, len(e.Missing))
+	for _, opt := range e.Missing {
+		missing = append(missing, opt.String())
+	}
+
+	switch e.Kind {
+	case ConstraintRequiredIf:
+		return fmt.Sprintf("option %s is required %s", missing[0], condition(e.Option.String(), e.values))
+	case ConstraintAtLeastOne:
+		return "at least one of options is required: " + strings.Join(missing, ", ")
+	case ConstraintRequires, ConstraintImplies:
+	}
+
+	return fmt.Sprintf("option %s requires %s", e.Option, strings.Join(missing, ", "))
+}
+
+// SetRequires declares that if the option optName is set, all of the required options have to be set too,
+// e.g. SetRequires("tls-key", "tls-cert"). If the constraint is violated, the parsing fails with
+// [*ConstraintError]. Options can be set by any source. Names can be either long or short.
+// SetRequires panics if any of the options was not added or no required options were passed.
+func (p *OptsParser) SetRequires(optName string, required ...string) *OptsParser {
+	if len(required) == 0 {
+		doPanic("No options required by option %q", optName)
+	}
+
+	return p.addConstraint(constraint{kind: ConstraintRequires, option: optName, options: required})
+}
+
+// SetRequiredIf declares that the option optName is required if the option condOpt is set. If condValues
+// are passed, the option is required only if the value of condOpt is one of them, e.g.
+// SetRequiredIf("node-id", "mode", "cluster") makes "--node-id" required by "--mode cluster". If the
+// constraint is violated, the parsing fails with [*ConstraintError]. Options can be set by any source.
+// Names can be either long or short. SetRequiredIf panics if any of the options was not added.
+func (p *OptsParser) SetRequiredIf(optName, condOpt string, condValues ...string) *OptsParser {
+	return p.addConstraint(constraint{
+		kind:		ConstraintRequiredIf,
+		option:		condOpt,
+		options:	[]string{optName},
+		values:		condValues,
+	})
+}
+
+// SetImplies declares that if the option optName is set, the implied options are set too, unless they were
+// set explicitly. A boolean option implies others only if it is set to true. Implied options are specified
+// in the form "name" for boolean options, which sets them to true, or "name=value", e.g.
+// SetImplies("all", "recursive", "depth=0"). Implied values are set after loading all sources, their source
+// is [SourceImplied]. SetImplies panics if any of the options was not added, no implied options were passed,
+// or the value of a non-boolean option is not specified.
+func (p *OptsParser) SetImplies(optName string, implied ...string) *OptsParser {
+	if len(implied) == 0 {
+		doPanic("No options implied by option %q", optName)
+	}
+
+	c := constraint{kind: ConstraintImplies, option: optName}
+	for _, opt := range implied {
+		name, value, hasValue := strings.Cut(opt, "=")
+		if !hasValue {
+			if f := p.Lookup(name); f != nil && !isBoolFlag(f) {
+				doPanic("Value of option %q implied by option %q is not specified", name, optName)
+			}
+			value = "true"
+		}
+
+		c.options = append(c.options, name)
+		c.values = append(c.values, value)
+	}
+
+	return p.addConstraint(c)
+}
+
+// SetAtLeastOne declares that at least one of the options has to be set, e.g. SetAtLeastOne("file", "url").
+// If the constraint is violated, the parsing fails with [*ConstraintError]. Options can be set by any source.
+// Names can be either long or short. SetAtLeastOne panics if less than two options are passed or any of
+// the options was not added.
+func (p *OptsParser) SetAtLeastOne(optNames ...string) *OptsParser {
+	if len(optNames) < 2 {
+		doPanic("Constraint \"at least one of\" requires at least two options, got %q", optNames)
+	}
+
+	return p.addConstraint(constraint{kind: ConstraintAtLeastOne, options: optNames})
+}
+
+// addConstraint replaces names in the constraint by long names and adds it to the parser
+func (p *OptsParser) addConstraint(c constraint) *OptsParser {
+	if c.option != "" {
+		c.option, _ = p.optDescr(c.option)
+	}
+
+	options := make([]string, 0, len(c.options))
+	for _, name := range c.options {
+		long, _ := p.optDescr(name)
+		options = append(options, long)
+	}
+	c.options = options
+
+	p.constraints = append(p.constraints, c)
+
+	return p
+}
+
+// applyImplies sets values of implied options
+func (p *OptsParser) applyImplies() []error {
+	errs := []error{}
+	for _, c := range p.constraints {
+		// Options can be set by the previous implication
+		set := p.setOptions()
+		if c.kind != ConstraintImplies || !set[c.option] {
+			continue
+		}
+		// Boolean option implies others only if it is true, e.g. not set by "--no-all"
+		if v, ok := optValue(p.Lookup(c.option)).(bool); ok && !v {
+			continue
+		}
+
+		// Implied values do not replace explicitly set values
+		src := Source{Kind: SourceImplied, Option: c.option}
+		for i, long := range c.options {
+			if set[long] {
+				continue
+			}
+
+			if err := p.setOpt(long, c.values[i], src); err != nil {
+				errs = append(errs, &InvalidValueError{
+					Option:	long,
+					Value:	c.values[i],
+					Cause:	err,
+					Source:	src,
+					dash:	dashes(long),
+				})
+			}
+		}
+	}
+
+	return errs
+}
+
+// constraintsErrors returns errors for each violated constraint, in order of the declaration
+func (p *OptsParser) constraintsErrors() []error {
+	set := p.setOptions()
+
+	errs := []error{}
+	for _, c := range p.constraints {
+		missing := []OptionName{}
+		for _, long := range c.options {
+			if !set[long] {
+				missing = append(missing, p.optionName(long))
+			}
+		}
+
+		var violated bool
+		switch c.kind {
+		case ConstraintRequires:
+			violated = set[c.option] && len(missing) != 0
+		case ConstraintRequiredIf:
+			violated = set[c.option] && len(missing) != 0 &&
+				(len(c.values) == 0 || contains(c.values, p.Lookup(c.option).Value.String()))
+		case ConstraintAtLeastOne:
+			violated = len(missing) == len(c.options)
+		case ConstraintImplies:
+			// Nothing to check
+		}
+
+		if !violated {
+			continue
+		}
+
+		err := &ConstraintError{Kind: c.kind, Missing: missing, values: c.values}
+		if c.option != "" {
+			err.Option = p.optionName(c.option)
+		}
+		errs = append(errs, err)
+	}
+
+	return errs
+}
+
+// descrConstraints writes the summary of constraints
+func (p *OptsParser) descrConstraints(out io.Writer) {
+	if len(p.constraints) == 0 {
+		return
+	}
+
+	fmt.Fprintf(out, "\nConstraints:\n")
+	for _, c := range p.constraints {
+		option := ""
+		if c.option != "" {
+			option = p.optionName(c.option).String()
+		}
+
+		options := make([]string, 0, len(c.options))
+		for i, long := range c.options {
+			name := p.optionName(long).String()
+			if c.kind == ConstraintImplies && !isBoolFlag(p.Lookup(long)) {
+				name += "=" + c.values[i]
+			}
+			options = append(options, name)
+		}
+
+		var descr string
+		switch c.kind {
+		case ConstraintRequires:
+			descr = option + " requires " + strings.Join(options, ", ")
+		case ConstraintRequiredIf:
+			descr = options[0] + " is required " + condition(option, c.values)
+		case ConstraintImplies:
+			descr = option + " implies " + strings.Join(options, ", ")
+		case ConstraintAtLeastOne:
+			descr = "at least one of " + strings.Join(options, ", ") + " is required"
+		}
+
+		fmt.Fprint(out, wrapText(descr, p.optIndent, p.helpIndent, p.width()))
+	}
+}
+
+// condition describes the condition of the ConstraintRequiredIf constraint
+func condition(option string, values []string) string {
+	if len(values) == 0 {
+		return "if " + option + " is set"
+	}
+
+	quoted := make([]string, 0, len(values))
+	for _, v := range values {
+		quoted = append(quoted, fmt.Sprintf("%q", v))
+	}
+
+	return "if " + option + " is " + strings.Join(quoted, " or ")
+}
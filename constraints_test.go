@@ -0,0 +1,277 @@
+package optsparser
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type testConstrOpts struct {
+	tlsKey		string
+	tlsCert		string
+	mode		string
+	nodeID		string
+	all			bool
+	recursive	bool
+	depth		int
+	file		string
+	url			string
+}
+
+// constrOpts adds options and constraints between them used by tests
+func constrOpts(p *OptsParser, to *testConstrOpts) *OptsParser {
+	p.AddString("tls-key", "TLS key", &to.tlsKey, "")
+	p.AddString("tls-cert", "TLS certificate", &to.tlsCert, "")
+	p.AddString("mode|m", "mode of operation", &to.mode, "single")
+	p.AddString("node-id", "identifier of the node", &to.nodeID, "")
+	p.AddNegatableBool("all|a", "process all", &to.all, false)
+	p.AddBool("recursive|r", "process recursively", &to.recursive, false)
+	p.AddInt("depth", "depth of processing", &to.depth, 1)
+	p.AddString("file|f", "input file", &to.file, "")
+	p.AddString("url", "input URL", &to.url, "")
+
+	return p.
+		SetRequires("tls-key", "tls-cert").
+		SetRequiredIf("node-id", "m", "cluster", "replica").
+		SetImplies("all", "r", "depth=0").
+		SetAtLeastOne("file", "url")
+}
+
+func TestConstraints(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct{
+		args	[]string
+		env		map[string]string
+		want	testConstrOpts
+	}{
+		`minimal`:	{
+			args:	[]string{`-f`, `in.txt`},
+			want:	testConstrOpts{mode: `single`, depth: 1, file: `in.txt`},
+		},
+		`requires-satisfied`:	{
+			args:	[]string{`--url`, `http://x`, `--tls-key`, `k`, `--tls-cert`, `c`},
+			want:	testConstrOpts{tlsKey: `k`, tlsCert: `c`, mode: `single`, depth: 1, url: `http://x`},
+		},
+		`required-if-other-value`:	{
+			args:	[]string{`-f`, `in.txt`, `-m`, `backup`},
+			want:	testConstrOpts{mode: `backup`, depth: 1, file: `in.txt`},
+		},
+		`required-if-satisfied-by-env`:	{
+			args:	[]string{`-f`, `in.txt`, `-m`, `cluster`},
+			env:	map[string]string{`APP_NODE_ID`: `n1`},
+			want:	testConstrOpts{mode: `cluster`, nodeID: `n1`, depth: 1, file: `in.txt`},
+		},
+		`implies`:	{
+			args:	[]string{`-f`, `in.txt`, `-a`},
+			want:	testConstrOpts{mode: `single`, all: true, recursive: true, depth: 0, file: `in.txt`},
+		},
+		`implies-does-not-replace-explicit`:	{
+			args:	[]string{`-f`, `in.txt`, `-a`, `--recursive=false`, `--depth`, `5`},
+			want:	testConstrOpts{mode: `single`, all: true, depth: 5, file: `in.txt`},
+		},
+		`negated-does-not-imply`:	{
+			args:	[]string{`-f`, `in.txt`, `--no-all`},
+			want:	testConstrOpts{mode: `single`, depth: 1, file: `in.txt`},
+		},
+		`false-does-not-imply`:	{
+			args:	[]string{`-f`, `in.txt`, `--all=false`},
+			want:	testConstrOpts{mode: `single`, depth: 1, file: `in.txt`},
+		},
+	}
+
+	for testN, test := range tests {
+		to := testConstrOpts{}
+		p := constrOpts(newTestParser(test.env), &to)
+
+		if err := p.ParseArgs(test.args); err != nil {
+			t.Errorf("%q parse failed: %v", testN, err)
+			continue
+		}
+		if to != test.want {
+			t.Errorf("%q incorrect Parse result: want - %#v got - %#v", testN, test.want, to)
+		}
+	}
+
+	// Source of implied value
+	p := constrOpts(newTestParser(nil), &testConstrOpts{})
+	if err := p.ParseArgs([]string{`-f`, `in.txt`, `--all`}); err != nil {
+		t.Errorf("parse failed: %v", err)
+		t.FailNow()
+	}
+	if got, want := p.Source("depth"), (Source{Kind: SourceImplied, Option: `all`}); got != want {
+		t.Errorf("incorrect source of %q: want - %#v got - %#v", "depth", want, got)
+	}
+	if got, want := p.Source("depth").String(), `implied by --all`; got != want {
+		t.Errorf("incorrect string representation of source: want - %q got - %q", want, got)
+	}
+
+	// Implied values are explained as effective values
+	tOut := &bytes.Buffer{}
+	p = constrOpts(newTestParser(nil), &testConstrOpts{}).SetOutput(tOut)
+	p.AddExplainConfig("explain-config", "print effective configuration")
+	if err := p.ParseArgs([]string{`--explain-config`, `-f`, `in.txt`, `--all`}); err != nil {
+		t.Errorf("parse with explanation failed: %v", err)
+		t.FailNow()
+	}
+	want := []string{`--recursive`, `true`, `implied`, `by`, `--all`}
+	found := false
+	for _, line := range strings.Split(tOut.String(), "\n") {
+		found = found || reflect.DeepEqual(strings.Fields(line), want)
+	}
+	if !found {
+		t.Errorf("explanation does not contain the implied value %q:\n%s", want, tOut.String())
+	}
+}
+
+func TestConstraintsFail(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct{
+		args	[]string
+		want	ConstraintError
+		text	string
+	}{
+		`requires`:	{
+			args:	[]string{`-f`, `in.txt`, `--tls-key`, `k`},
+			want:	ConstraintError{Kind: ConstraintRequires, Option: OptionName{Long: `tls-key`},
+						Missing: []OptionName{{Long: `tls-cert`}}},
+			text:	`option --tls-key requires --tls-cert`,
+		},
+		`required-if`:	{
+			args:	[]string{`-f`, `in.txt`, `--mode=replica`},
+			want:	ConstraintError{Kind: ConstraintRequiredIf, Option: OptionName{Long: `mode`, Short: `m`},
+						Missing: []OptionName{{Long: `node-id`}}},
+			text:	`option --node-id is required if --mode is "cluster" or "replica"`,
+		},
+		`at-least-one`:	{
+			args:	[]string{},
+			want:	ConstraintError{Kind: ConstraintAtLeastOne,
+						Missing: []OptionName{{Long: `file`, Short: `f`}, {Long: `url`}}},
+			text:	`at least one of options is required: --file, --url`,
+		},
+	}
+
+	for testN, test := range tests {
+		err := constrOpts(newTestParser(nil), &testConstrOpts{}).ParseArgs(test.args)
+
+		var cErr *ConstraintError
+		if !errors.As(err, &cErr) {
+			t.Errorf("%q unexpected error type: want - %T got - %T (%v)", testN, cErr, err, err)
+			continue
+		}
+		if cErr.Kind != test.want.Kind || cErr.Option != test.want.Option ||
+				!reflect.DeepEqual(cErr.Missing, test.want.Missing) {
+			t.Errorf("%q incorrect error: want - %#v got - %#v", testN, test.want, *cErr)
+		}
+		if err.Error() != test.text {
+			t.Errorf("%q unexpected error: want - %q got - %q", testN, test.text, err.Error())
+		}
+	}
+
+	// Errors are reported in order of the declaration
+	p := constrOpts(newTestParser(nil), &testConstrOpts{}).SetReportAll(true)
+	err := p.ParseArgs([]string{`-m`, `cluster`, `--tls-key`, `k`})
+
+	var errs ParseErrors
+	if !errors.As(err, &errs) {
+		t.Errorf("unexpected error type: want - %T got - %T (%v)", errs, err, err)
+		t.FailNow()
+	}
+
+	want := []string{
+		`option --tls-key requires --tls-cert`,
+		`option --node-id is required if --mode is "cluster" or "replica"`,
+		`at least one of options is required: --file, --url`,
+	}
+	got := make([]string, 0, len(errs))
+	for _, e := range errs {
+		got = append(got, e.Error())
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("incorrect list of errors:\nwant - %#v\ngot  - %#v", want, got)
+	}
+
+	// Invalid implied value
+	p = newTestParser(nil)
+	p.AddBool("all", "process all", new(bool), false)
+	p.AddInt("depth", "depth of processing", new(int), 1)
+	p.SetImplies("all", "depth=x")
+	if err := p.ParseArgs([]string{`--all`}); err == nil ||
+			err.Error() != `invalid value "x" for flag --depth: parse error` {
+		t.Errorf("unexpected error of invalid implied value: %v", err)
+	}
+}
+
+func TestConstraintsUsage(t *testing.T) {
+	t.Parallel()
+
+	tOut := &bytes.Buffer{}
+	p := newParser(stubApp).SetOutput(tOut)
+	p.AddString("tls-key", "TLS key", new(string), "")
+	p.AddString("tls-cert", "TLS certificate", new(string), "")
+	p.AddBool("cluster", "cluster mode", new(bool), false)
+	p.AddString("node-id|n", "identifier of the node", new(string), "")
+	p.AddBool("all", "process all", new(bool), false)
+	p.AddBool("recursive|r", "process recursively", new(bool), false)
+	p.AddInt("depth", "depth of processing", new(int), 1)
+	p.
+		SetRequires("tls-key", "tls-cert").
+		SetRequiredIf("node-id", "cluster").
+		SetImplies("all", "recursive", "depth=0").
+		SetAtLeastOne("tls-key", "cluster")
+	p.WriteUsage(tOut)
+
+	want := `
+Usage of ` + stubApp + `:
+    --tls-key string
+      TLS key (default: "")
+    --tls-cert string
+      TLS certificate (default: "")
+    --cluster[=true|false]
+      cluster mode (default: false)
+    --node-id string, -n string
+      identifier of the node (default: "")
+    --all[=true|false]
+      process all (default: false)
+    --recursive[=true|false], -r[=true|false]
+      process recursively (default: false)
+    --depth int
+      depth of processing (default: 1)
+
+Constraints:
+    --tls-key requires --tls-cert
+    --node-id is required if --cluster is set
+    --all implies --recursive, --depth=0
+    at least one of --tls-key, --cluster is required
+`
+	if tOut.String() != want {
+		t.Errorf("incorrect usage output: want -\n%s\ngot -\n%s", want, tOut.String())
+	}
+}
+
+func TestConstraintsPanic(t *testing.T) {
+	t.Parallel()
+
+	for testN, add := range map[string]func(p *OptsParser){
+		`requires-nothing`:		func(p *OptsParser) { p.SetRequires("tls-key") },
+		`requires-unknown`:		func(p *OptsParser) { p.SetRequires("tls-key", "tls-ca") },
+		`required-if-unknown`:	func(p *OptsParser) { p.SetRequiredIf("node-id", "cluster") },
+		`implies-nothing`:		func(p *OptsParser) { p.SetImplies("all") },
+		`implies-no-value`:		func(p *OptsParser) { p.SetImplies("all", "depth") },
+		`implies-unknown`:		func(p *OptsParser) { p.SetImplies("all", "force") },
+		`at-least-one-single`:	func(p *OptsParser) { p.SetAtLeastOne("file") },
+	} {
+		func() {
+			defer func() {
+				if _, ok := recover().(OptsPanic); !ok {
+					t.Errorf("%q must panic with OptsPanic", testN)
+				}
+			}()
+
+			add(constrOpts(newTestParser(nil), &testConstrOpts{}))
+		}()
+	}
+}
@@ -1,8 +1,10 @@
 package optsparser
 
 const sepPrefix = "\u0000\u0000separator\u0000\u0000"
-const optIndent = "    "
-const helpIndent = optIndent + "  "
+const optIndentDefault = "    "
+const helpIndentDefault = optIndentDefault + "  "
+const envPosixlyCorrect = "POSIXLY_CORRECT"
+const mapDelimDefault = ","
 
 const (
 	typeBool		=	"bool"
@@ -14,10 +16,15 @@ const (
 	typeFloat64		=	"float64"
 	typeDuration	=	"duration"
 	typeVal			=	"value"
+	typeCounter		=	"counter"
 	typeSeparator	=	"sep"
 )
 
 type optDescr struct {
 	optType		string
 	short		string
+	negatable	bool
+	env			string
+	secret		bool
+	validators	[]Validator
 }
@@ -4,6 +4,10 @@ Package optsparser enhances the functionality of the standard [flag] package.
 Package key features are:
 
  * Support long and short form of the same option
+ * Supports getopt-like clusters of short options: "-vxf archive.tar", "-ofile", "-j4"
+ * Supports binding options to struct fields described by tags
+ * Supports subcommands with their own options, global options are inherited by commands
+ * Supports declared positional arguments bound to typed variables
  * Supports required options to save your time from
    checking were they specified by command line or not
  * Improved Usage function - option references are displayed in the order of their addition,
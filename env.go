@@ -0,0 +1,101 @@
+package optsparser
+
+import (
+	"strings"
+)
+
+// sourceAware is implemented by values that accumulate the values specified in the single source
+// (e.g. command line), but have to replace values set by the previous sources (e.g. environment)
+type sourceAware interface {
+	newSource()
+}
+
+// SetEnv binds the option optName to the environment variable env. If the variable is set,
+// its value is assigned to the option after setting defaults, but before parsing the command
+// line, so the command line has priority over the environment. A required option is considered
+// as specified if the variable is set. The variable name is printed in the Usage output. The optName
+// can be either long or short name of the option. SetEnv panics if the option was not added.
+func (p *OptsParser) SetEnv(optName, env string) *OptsParser {
+	_, descr := p.optDescr(optName)
+	descr.env = env
+
+	return p
+}
+
+// SetEnvPrefix enables automatic binding of options to environment variables. Each option that has
+// a long name and has no variable set by [OptsParser.SetEnv] is bound to the variable named by the
+// prefix and the long name of the option in upper case, with dashes replaced by underscores,
+// e.g. with the prefix "APP" the option "config-path" is bound to APP_CONFIG_PATH.
+func (p *OptsParser) SetEnvPrefix(prefix string) *OptsParser {
+	p.envPrefix = strings.TrimSuffix(prefix, "_")
+
+	return p
+}
+
+// envName returns the name of the environment variable bound to the long option
+func (p *OptsParser) envName(long string) string {
+	descr, ok := p.longOpts[long]
+
+	switch {
+	case !ok || descr.optType == typeSeparator:
+		// Separators are not options
+		return ""
+	case descr.env != "":
+		// Explicitly bound variable
+		return descr.env
+	case p.envPrefix != "" && len(long) > 1:
+		return p.envPrefix + "_" + strings.ToUpper(strings.ReplaceAll(long, "-", "_"))
+	}
+
+	// Option is not bound to environment
+	return ""
+}
+
+// applyEnv sets values of options from the bound environment variables
+func (p *OptsParser) applyEnv() error {
+	for _, long := range p.orderedList {
+		env := p.envName(long)
+		if env == "" {
+			continue
+		}
+
+		value, ok := p.lookupEnv(env)
+		if !ok {
+			continue
+		}
+
+		src := Source{Kind: SourceEnv, Env: env}
+		if err := p.setOpt(long, value, src); err != nil {
+			if err := p.report(&InvalidValueError{Option: long, Value: value, Cause: err, Source: src}); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Values from the next source have to replace values from the environment
+	p.newSource()
+
+	return nil
+}
+
+// newSource notifies all values that the next values are from the new source
+func (p *OptsParser) newSource() {
+	for _, long := range p.orderedList {
+		if sa, ok := p.Lookup(long).Value.(sourceAware); ok {
+			sa.newSource()
+		}
+	}
+}
+
+// optDescr returns long name and description of the option optName,
+// which can be either long or short. It panics if the option was not added.
+func (p *OptsParser) optDescr(optName string) (string, *optDescr) {
+	optName = p.longName(optName)
+
+	descr, ok := p.longOpts[optName]
+	if !ok || descr.optType == typeSeparator {
+		doPanic("Option %q was not added", optName)
+	}
+
+	return optName, descr
+}
@@ -0,0 +1,149 @@
+package optsparser
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+type testEnvOpts struct {
+	config	string
+	workers	int
+	debug	bool
+	tags	[]string
+}
+
+// envOpts adds options used by tests of environment variables
+func envOpts(p *OptsParser, to *testEnvOpts) *OptsParser {
+	p.AddString("config-path|c", "path to configuration", &to.config, "/etc/app.conf")
+	p.AddInt("workers|w", "number of workers", &to.workers, 1)
+	p.AddBool("d", "debug mode", &to.debug, false)
+	p.AddStrings("tags", "list of tags", &to.tags, nil)
+	p.SetDelimiter("tags", ",")
+
+	return p
+}
+
+func TestEnv(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct{
+		env		map[string]string
+		prefix	string
+		args	[]string
+		want	testEnvOpts
+	}{
+		`no-env`:	{
+			args:	[]string{},
+			want:	testEnvOpts{config: "/etc/app.conf", workers: 1, tags: []string{}},
+		},
+		`explicit-env`:	{
+			env:	map[string]string{`CONFIG`: `/tmp/app.conf`, `DEBUG`: `true`, `APP_WORKERS`: `10`},
+			args:	[]string{},
+			want:	testEnvOpts{config: "/tmp/app.conf", workers: 1, debug: true, tags: []string{}},
+		},
+		`prefix-env`:	{
+			// APP_CONFIG_PATH is ignored because config-path is explicitly bound to CONFIG
+			env:	map[string]string{`APP_WORKERS`: `10`, `APP_CONFIG_PATH`: `/opt/app.conf`, `APP_TAGS`: `a,b`},
+			prefix:	`APP_`,
+			args:	[]string{},
+			want:	testEnvOpts{config: "/etc/app.conf", workers: 10, tags: []string{`a`, `b`}},
+		},
+		`args-override-env`:	{
+			env:	map[string]string{`APP_WORKERS`: `10`, `CONFIG`: `/opt/app.conf`, `APP_TAGS`: `a,b`},
+			prefix:	`APP`,
+			args:	[]string{`-w`, `5`, `--tags`, `c`, `--tags`, `d`},
+			want:	testEnvOpts{config: "/opt/app.conf", workers: 5, tags: []string{`c`, `d`}},
+		},
+	}
+
+	for testN, test := range tests {
+		testN, test := testN, test
+		t.Run(testN, func(t *testing.T) {
+			t.Parallel()
+
+			to := testEnvOpts{}
+			p := envOpts(newTestParser(test.env), &to).
+				SetEnv("c", "CONFIG").
+				SetEnv("d", "DEBUG").
+				SetEnvPrefix(test.prefix)
+
+			if err := p.ParseArgs(test.args); err != nil {
+				t.Errorf("%q parse failed: %v", testN, err)
+				return
+			}
+
+			if !reflect.DeepEqual(to, test.want) {
+				t.Errorf("%q incorrect Parse result: want - %#v got - %#v", testN, test.want, to)
+			}
+		})
+	}
+}
+
+func TestEnvRequired(t *testing.T) {
+	t.Parallel()
+
+	// Required option is satisfied by environment
+	p := envOpts(newTestParser(map[string]string{`APP_CONFIG_PATH`: `/opt/app.conf`}, "config-path"), &testEnvOpts{})
+	if err := p.ParseArgs([]string{}); err != nil {
+		t.Errorf("parse failed: %v", err)
+	}
+
+	// Required option is missing
+	p = envOpts(newTestParser(map[string]string{}, "config-path"), &testEnvOpts{})
+	want := `required option(s) is missing: --config-path`
+	if err := p.ParseArgs([]string{}); err == nil || err.Error() != want {
+		t.Errorf("parse returned unexpected error: want - %q got - %v", want, err)
+	}
+}
+
+func TestEnvFail(t *testing.T) {
+	t.Parallel()
+
+	p := envOpts(newTestParser(map[string]string{`APP_WORKERS`: `ten`}), &testEnvOpts{})
+	want := `invalid value "ten" for flag --workers from environment variable APP_WORKERS: parse error`
+	if err := p.ParseArgs([]string{}); err == nil || err.Error() != want {
+		t.Errorf("parse returned unexpected error: want - %q got - %v", want, err)
+	}
+}
+
+func TestEnvUsage(t *testing.T) {
+	t.Parallel()
+
+	tOut := &bytes.Buffer{}
+	p := envOpts(newTestParser(nil, "config-path"), &testEnvOpts{}).
+		SetEnv("d", "DEBUG").
+		SetOutput(tOut)
+	p.Usage()
+
+	want := `
+Usage of ` + stubApp + `:
+    --config-path string, -c string
+      path to configuration (env: APP_CONFIG_PATH, required option)
+    --workers int, -w int
+      number of workers (env: APP_WORKERS, default: 1)
+    -d[=true|false]
+      debug mode (env: DEBUG, default: false)
+    --tags string[,...]
+      list of tags (can be repeated, env: APP_TAGS, default: [])
+`
+	if tOut.String() != want {
+		t.Errorf("output produced by Usage is different from expexted, see below:\n" +
+			"\n-------- Want --------\n%s\n" +
+			"-------- Got --------\n%s\n",
+			want, tOut.String(),
+		)
+	}
+}
+
+func TestSetEnvPanic(t *testing.T) {
+	t.Parallel()
+
+	defer func() {
+		if _, ok := recover().(OptsPanic); !ok {
+			t.Errorf("SetEnv for unknown option did not cause a panic, but it must")
+		}
+	}()
+
+	newParser(stubApp).SetEnv("unknown", "UNKNOWN")
+}
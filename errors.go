@@ -0,0 +1,194 @@
+package optsparser
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ParseErrors is the list of errors returned by the parser in the report all mode,
+// see [OptsParser.SetReportAll]. Errors are ordered as they were found.
+type ParseErrors []error
+
+func (e ParseErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+
+	// The same format as used by errors.Join
+	return strings.Join(msgs, "\n")
+}
+
+// Unwrap returns the list of errors, it is used by errors.Is and errors.As since Go 1.20
+func (e ParseErrors) Unwrap() []error {
+	return e
+}
+
+// Is reports whether any error in the list matches target, it makes errors.Is
+// work with the list on Go versions before 1.20
+func (e ParseErrors) Is(target error) bool {
+	for _, err := range e {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// As finds the first error in the list that matches target, it makes errors.As
+// work with the list on Go versions before 1.20
+func (e ParseErrors) As(target any) bool {
+	for _, err := range e {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// report collects the error in the report all mode, otherwise
+// it returns the error back to stop the parsing
+func (p *OptsParser) report(err error) error {
+	if !p.reportAll {
+		return err
+	}
+
+	p.errs = append(p.errs, err)
+
+	return nil
+}
+
+// OptionName contains both forms of the option name, without dashes. One of them
+// is empty if the option has only the long or only the short form.
+type OptionName struct {
+	Long	string
+	Short	string
+}
+
+// String returns the name of the option with dashes, the long form is preferred
+func (n OptionName) String() string {
+	if n.Long != "" {
+		return "--" + n.Long
+	}
+
+	return "-" + n.Short
+}
+
+// optionName returns both forms of the name of the option, which is registered by the name
+func (p *OptsParser) optionName(name string) OptionName {
+	if len(name) == 1 {
+		// Only short form exists
+		return OptionName{Short: name}
+	}
+
+	return OptionName{Long: name, Short: p.longOpts[name].short}
+}
+
+// MissingRequiredError is returned by the parser if some of the required options were not set
+type MissingRequiredError struct {
+	Options	[]OptionName	// missing options sorted by names
+}
+
+func (e *MissingRequiredError) Error() string {
+	names := make([]string, 0, len(e.Options))
+	for _, opt := range e.Options {
+		names = append(names, opt.String())
+	}
+
+	return "required option(s) is missing: " + strings.Join(names, ", ")
+}
+
+// InvalidValueError is returned by the parser if the value of an option cannot be set
+type InvalidValueError struct {
+	Option	string	// name of the option as it was specified, without dashes
+	Value	string	// raw value of the option
+	Cause	error	// error returned by the option value
+	Source	Source	// where the value came from
+	dash	string	// dashes used before the option name in the arguments list
+}
+
+func (e *InvalidValueError) Error() string {
+	switch e.Source.Kind {
+	case SourceEnv:
+		return fmt.Sprintf("invalid value %q for flag %s%s from environment variable %s: %v",
+			e.Value, dashes(e.Option), e.Option, e.Source.Env, e.Cause)
+	case SourceFile:
+		// Location in the file is added by the caller
+		return fmt.Sprintf("invalid value %q for option %q: %v", e.Value, e.Option, e.Cause)
+	case SourceArgs, SourceImplied, SourceDefault:
+	}
+
+	return fmt.Sprintf("invalid value %q for flag %s%s: %v", e.Value, e.dash, e.Option, e.Cause)
+}
+
+func (e *InvalidValueError) Unwrap() error {
+	return e.Cause
+}
+
+// UnknownOptionError is returned by the parser if an option was not added to the parser
+type UnknownOptionError struct {
+	Option	string	// name of the option as it was specified, without dashes
+	Cluster	string	// cluster of short options which contains the option, if any
+	dash	string	// dashes used before the option name, empty if the option came from the configuration file
+}
+
+func (e *UnknownOptionError) Error() string {
+	switch {
+	case e.dash == "":
+		return fmt.Sprintf("unknown option %q", e.Option)
+	case e.Cluster != "":
+		return fmt.Sprintf("flag provided but not defined: %s%s (in cluster -%s)", e.dash, e.Option, e.Cluster)
+	}
+
+	return fmt.Sprintf("flag provided but not defined: %s%s", e.dash, e.Option)
+}
+
+// ExclusiveError is returned by the parser if several mutually exclusive options were set,
+// see [OptsParser.SetExclusive]
+type ExclusiveError struct {
+	Options	[]OptionName	// options that were set together, in order of the declaration
+}
+
+func (e *ExclusiveError) Error() string {
+	names := make([]string, 0, len(e.Options))
+	for _, opt := range e.Options {
+		names = append(names, opt.String())
+	}
+
+	return "options are mutually exclusive: " + strings.Join(names, ", ")
+}
+
+// ArgCountError is returned by the parser if the number of positional arguments does not match
+// declared arguments, see [AddArg]
+type ArgCountError struct {
+	Arg	string	// name of the first missing argument, empty if there are too many arguments
+	Min	int		// minimal number of arguments
+	Max	int		// maximal number of arguments, 0 if unlimited
+	Got	int		// number of passed arguments
+}
+
+func (e *ArgCountError) Error() string {
+	if e.Arg != "" {
+		return fmt.Sprintf("missing argument %s (expected at least %d argument(s), got %d)", e.Arg, e.Min, e.Got)
+	}
+
+	return fmt.Sprintf("too many arguments (expected at most %d argument(s), got %d)", e.Max, e.Got)
+}
+
+// InvalidArgError is returned by the parser if the value of a positional argument cannot be parsed
+type InvalidArgError struct {
+	Arg		string	// name of the argument
+	Value	string	// raw value of the argument
+	Cause	error	// error of parsing the value
+}
+
+func (e *InvalidArgError) Error() string {
+	return fmt.Sprintf("invalid value %q for argument %s: %v", e.Value, e.Arg, e.Cause)
+}
+
+func (e *InvalidArgError) Unwrap() error {
+	return e.Cause
+}
@@ -0,0 +1,238 @@
+package optsparser
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// errorsRequired are required options of tests of errors
+//nolint:gochecknoglobals // the list is shared by tests
+var errorsRequired = []string{"name", "w", "output"}
+
+// errorsOpts adds options used by tests of errors
+func errorsOpts(p *OptsParser) *OptsParser {
+	p.AddConfig("config", "path to configuration", "")
+	p.AddString("name|n", "name of instance", new(string), "")
+	p.AddInt("w", "number of workers", new(int), 1)
+	p.AddString("output", "output file", new(string), "")
+	p.AddInt("jobs|j", "number of jobs", new(int), 1)
+	p.AddBool("verbose|v", "verbose output", new(bool), false)
+
+	return p
+}
+
+func TestMissingRequiredError(t *testing.T) {
+	t.Parallel()
+
+	p := errorsOpts(newTestParser(nil, errorsRequired...))
+	err := p.ParseArgs([]string{`-w`, `2`})
+
+	var mrErr *MissingRequiredError
+	if !errors.As(err, &mrErr) {
+		t.Errorf("unexpected error type: want - %T got - %T (%v)", mrErr, err, err)
+		t.FailNow()
+	}
+
+	want := []OptionName{{Long: `name`, Short: `n`}, {Long: `output`}}
+	if !reflect.DeepEqual(mrErr.Options, want) {
+		t.Errorf("incorrect missing options: want - %#v got - %#v", want, mrErr.Options)
+	}
+	if want := `required option(s) is missing: --name, --output`; err.Error() != want {
+		t.Errorf("unexpected error: want - %q got - %q", want, err.Error())
+	}
+
+	// Short-only option
+	p = errorsOpts(newTestParser(nil, errorsRequired...))
+	err = p.ParseArgs([]string{`-n`, `x`, `--output`, `out`})
+	if want := `required option(s) is missing: -w`; err == nil || err.Error() != want {
+		t.Errorf("unexpected error: want - %q got - %v", want, err)
+	}
+
+	// Parser can be used again after parsing
+	if err := p.ParseArgs([]string{`-w`, `2`}); err != nil {
+		t.Errorf("repeated parse failed: %v", err)
+	}
+}
+
+func TestRequiredRepeatedParse(t *testing.T) {
+	t.Parallel()
+
+	// Required options set by the previous parsing are not treated as options that were not added
+	p := errorsOpts(newTestParser(nil, errorsRequired...))
+	for i := 1; i <= 2; i++ {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					t.Errorf("parse #%d panicked: %v", i, r)
+				}
+			}()
+
+			if err := p.ParseArgs([]string{`-n`, `x`, `-w`, `2`, `--output`, `out`}); err != nil {
+				t.Errorf("parse #%d failed: %v", i, err)
+			}
+		}()
+	}
+}
+
+func TestInvalidValueError(t *testing.T) {
+	t.Parallel()
+
+	cfg := writeConfig(t, "app.conf", "\njobs = x\n")
+
+	tests := map[string]struct{
+		args	[]string
+		env		map[string]string
+		want	InvalidValueError
+		text	string
+	}{
+		`args`:	{
+			args:	[]string{`-j4x`},
+			want:	InvalidValueError{Option: `j`, Value: `4x`, Source: Source{Kind: SourceArgs}},
+			text:	`invalid value "4x" for flag -j: parse error`,
+		},
+		`env`:	{
+			env:	map[string]string{`APP_JOBS`: `y`},
+			want:	InvalidValueError{Option: `jobs`, Value: `y`, Source: Source{Kind: SourceEnv, Env: `APP_JOBS`}},
+			text:	`invalid value "y" for flag --jobs from environment variable APP_JOBS: parse error`,
+		},
+		`config`:	{
+			args:	[]string{`--config`, cfg},
+			want:	InvalidValueError{Option: `jobs`, Value: `x`, Source: Source{Kind: SourceFile, File: cfg, Line: 2}},
+			text:	cfg + `:2: invalid value "x" for option "jobs": parse error`,
+		},
+	}
+
+	for testN, test := range tests {
+		p := errorsOpts(newTestParser(test.env, errorsRequired...))
+		err := p.ParseArgs(test.args)
+
+		var ivErr *InvalidValueError
+		if !errors.As(err, &ivErr) {
+			t.Errorf("%q unexpected error type: want - %T got - %T (%v)", testN, ivErr, err, err)
+			continue
+		}
+		if ivErr.Option != test.want.Option || ivErr.Value != test.want.Value || ivErr.Source != test.want.Source {
+			t.Errorf("%q incorrect error: want - %#v got - %#v", testN, test.want, *ivErr)
+		}
+		if ivErr.Cause == nil || errors.Unwrap(ivErr) != ivErr.Cause {
+			t.Errorf("%q error must wrap the cause, got %v", testN, ivErr.Cause)
+		}
+		if err.Error() != test.text {
+			t.Errorf("%q unexpected error: want - %q got - %q", testN, test.text, err.Error())
+		}
+	}
+}
+
+func TestUnknownOptionError(t *testing.T) {
+	t.Parallel()
+
+	cfg := writeConfig(t, "app.conf", "unknown = 1\n")
+
+	tests := map[string]struct{
+		args	[]string
+		want	UnknownOptionError
+		text	string
+	}{
+		`long`:		{
+			args:	[]string{`--unknown`},
+			want:	UnknownOptionError{Option: `unknown`},
+			text:	`flag provided but not defined: --unknown`,
+		},
+		`cluster`:	{
+			args:	[]string{`-vyj2`},
+			want:	UnknownOptionError{Option: `y`, Cluster: `vyj2`},
+			text:	`flag provided but not defined: -y (in cluster -vyj2)`,
+		},
+		`config`:	{
+			args:	[]string{`--config`, cfg},
+			want:	UnknownOptionError{Option: `unknown`},
+			text:	cfg + `:1: unknown option "unknown"`,
+		},
+	}
+
+	for testN, test := range tests {
+		p := errorsOpts(newTestParser(nil, errorsRequired...))
+		err := p.ParseArgs(test.args)
+
+		var uoErr *UnknownOptionError
+		if !errors.As(err, &uoErr) {
+			t.Errorf("%q unexpected error type: want - %T got - %T (%v)", testN, uoErr, err, err)
+			continue
+		}
+		if uoErr.Option != test.want.Option || uoErr.Cluster != test.want.Cluster {
+			t.Errorf("%q incorrect error: want - %#v got - %#v", testN, test.want, *uoErr)
+		}
+		if err.Error() != test.text {
+			t.Errorf("%q unexpected error: want - %q got - %q", testN, test.text, err.Error())
+		}
+	}
+}
+
+func TestReportAll(t *testing.T) {
+	t.Parallel()
+
+	cfg := writeConfig(t, "app.conf", "jobs = x\nunknown = 1\n")
+
+	tOut := &bytes.Buffer{}
+	p := errorsOpts(newTestParser(map[string]string{`APP_JOBS`: `y`}, errorsRequired...)).SetReportAll(true).SetUsageOnFail(true).SetOutput(tOut)
+	err := p.ParseArgs([]string{`--config`, cfg, `--unknown`, `-vz`, `--jobs=z`, `-n`, `x`, `-w`, `1`})
+
+	var errs ParseErrors
+	if !errors.As(err, &errs) {
+		t.Errorf("unexpected error type: want - %T got - %T (%v)", errs, err, err)
+		t.FailNow()
+	}
+
+	want := []string{
+		`flag provided but not defined: --unknown`,
+		`flag provided but not defined: -z (in cluster -vz)`,
+		cfg + `:1: invalid value "x" for option "jobs": parse error`,
+		cfg + `:2: unknown option "unknown"`,
+		`invalid value "y" for flag --jobs from environment variable APP_JOBS: parse error`,
+		`invalid value "z" for flag --jobs: parse error`,
+		`required option(s) is missing: --output`,
+	}
+	got := make([]string, 0, len(errs))
+	for _, e := range errs {
+		got = append(got, e.Error())
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("incorrect list of errors:\nwant - %#v\ngot  - %#v", want, got)
+	}
+
+	// Errors can be inspected
+	var mrErr *MissingRequiredError
+	if !errors.As(err, &mrErr) || mrErr.Options[0].Long != `output` {
+		t.Errorf("missing required option must be found in the list of errors")
+	}
+	var ivErr *InvalidValueError
+	if !errors.As(err, &ivErr) || ivErr.Option != `jobs` {
+		t.Errorf("invalid value must be found in the list of errors")
+	}
+
+	// Usage prints the list
+	wantOut := "\nUsage ERROR:\n"
+	for _, e := range want {
+		wantOut += "  - " + e + "\n"
+	}
+	if out := tOut.String(); !strings.HasPrefix(out, wantOut) {
+		t.Errorf("incorrect usage output: want prefix -\n%s\ngot -\n%s", wantOut, out)
+	}
+
+	// Only missing required options
+	p = errorsOpts(newTestParser(nil, errorsRequired...)).SetReportAll(true)
+	err = p.ParseArgs([]string{`-n`, `x`, `-w`, `1`})
+	if !errors.As(err, &errs) || len(errs) != 1 || !errors.As(err, &mrErr) {
+		t.Errorf("unexpected error: %#v", err)
+	}
+
+	// Help request stops parsing
+	p = errorsOpts(newTestParser(nil, errorsRequired...)).SetReportAll(true)
+	if err := p.ParseArgs([]string{`--unknown`, `--help`, `--jobs=x`}); !errors.Is(err, flag.ErrHelp) {
+		t.Errorf("unexpected error: want - %v got - %v", flag.ErrHelp, err)
+	}
+}
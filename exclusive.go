@@ -0,0 +1,98 @@
+package optsparser
+
+import (
+	"flag"
+)
+
+// SetExclusive declares the group of mutually exclusive options, i.e. only one of them can be set.
+// Options can be set by any source - the command line, the environment or the configuration file.
+// The optNames can be either long or short names of options, both forms of an option are considered.
+// SetExclusive can be called several times to declare several groups. If more than one option of
+// a group is set, the parsing fails with [*ExclusiveError]. The Usage output of each option of
+// the group lists other options of the group.
+//
+// SetExclusive panics if less than two options are passed or any of the options was not added.
+func (p *OptsParser) SetExclusive(optNames ...string) *OptsParser {
+	if len(optNames) < 2 {
+		doPanic("Group of mutually exclusive options requires at least two options, got %q", optNames)
+	}
+
+	group := make([]string, 0, len(optNames))
+	for _, name := range optNames {
+		long, _ := p.optDescr(name)
+		group = append(group, long)
+	}
+
+	p.exclusive = append(p.exclusive, group)
+
+	return p
+}
+
+// exclusiveErrors returns errors for each group of mutually exclusive options with several options set
+func (p *OptsParser) exclusiveErrors() []error {
+	if len(p.exclusive) == 0 {
+		return nil
+	}
+
+	set := p.setOptions()
+
+	errs := []error{}
+	for _, group := range p.exclusive {
+		names := []OptionName{}
+		for _, long := range group {
+			if set[long] {
+				names = append(names, p.optionName(long))
+			}
+		}
+
+		if len(names) > 1 {
+			errs = append(errs, &ExclusiveError{Options: names})
+		}
+	}
+
+	return errs
+}
+
+// exclusiveWith returns names of options that are mutually exclusive with the option
+func (p *OptsParser) exclusiveWith(long string) []string {
+	names := []string{}
+	seen := map[string]bool{long: true}
+
+	for _, group := range p.exclusive {
+		if !contains(group, long) {
+			continue
+		}
+
+		for _, other := range group {
+			if !seen[other] {
+				seen[other] = true
+				names = append(names, p.optionName(other).String())
+			}
+		}
+	}
+
+	return names
+}
+
+// setOptions returns long names of options that were set
+func (p *OptsParser) setOptions() map[string]bool {
+	set := map[string]bool{}
+	p.Visit(func(f *flag.Flag) {
+		// Options which values were rejected by validators are not set
+		if long := p.longName(f.Name); !p.rejected[long] {
+			set[long] = true
+		}
+	})
+
+	return set
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,129 @@
+package optsparser
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// exclusiveOpts adds options and groups of mutually exclusive options used by tests
+func exclusiveOpts(p *OptsParser) *OptsParser {
+	p.AddBool("json|j", "JSON output", new(bool), false)
+	p.AddBool("yaml|y", "YAML output", new(bool), false)
+	p.AddBool("table", "table output", new(bool), false)
+	p.AddString("password", "password", new(string), "")
+	p.AddString("password-file|P", "file with password", new(string), "")
+
+	return p.SetExclusive("json", "yaml", "table").SetExclusive("password", "P")
+}
+
+func TestExclusive(t *testing.T) {
+	t.Parallel()
+
+	// Successful cases
+	for _, args := range [][]string{
+		{},
+		{`--json`, `--password`, `secret`},
+		{`-y`, `-P`, `pass.txt`},
+		{`--table`, `--table`},
+	} {
+		if err := exclusiveOpts(newTestParser(nil)).ParseArgs(args); err != nil {
+			t.Errorf("parse of %#v failed: %v", args, err)
+		}
+	}
+
+	// Failed cases
+	tests := map[string]struct{
+		args	[]string
+		env		map[string]string
+		want	[]OptionName
+		text	string
+	}{
+		`long-and-short`:	{
+			args:	[]string{`-y`, `--json`},
+			want:	[]OptionName{{Long: `json`, Short: `j`}, {Long: `yaml`, Short: `y`}},
+			text:	`options are mutually exclusive: --json, --yaml`,
+		},
+		`all-of-group`:		{
+			args:	[]string{`--table`, `-jy`},
+			want:	[]OptionName{{Long: `json`, Short: `j`}, {Long: `yaml`, Short: `y`}, {Long: `table`}},
+			text:	`options are mutually exclusive: --json, --yaml, --table`,
+		},
+		`from-env`:			{
+			args:	[]string{`-P`, `pass.txt`},
+			env:	map[string]string{`APP_PASSWORD`: `secret`},
+			want:	[]OptionName{{Long: `password`}, {Long: `password-file`, Short: `P`}},
+			text:	`options are mutually exclusive: --password, --password-file`,
+		},
+	}
+
+	for testN, test := range tests {
+		err := exclusiveOpts(newTestParser(test.env)).ParseArgs(test.args)
+
+		var exErr *ExclusiveError
+		if !errors.As(err, &exErr) {
+			t.Errorf("%q unexpected error type: want - %T got - %T (%v)", testN, exErr, err, err)
+			continue
+		}
+		if !reflect.DeepEqual(exErr.Options, test.want) {
+			t.Errorf("%q incorrect options: want - %#v got - %#v", testN, test.want, exErr.Options)
+		}
+		if err.Error() != test.text {
+			t.Errorf("%q unexpected error: want - %q got - %q", testN, test.text, err.Error())
+		}
+	}
+
+	// All groups are reported in the report all mode
+	p := exclusiveOpts(newTestParser(nil)).SetReportAll(true)
+	err := p.ParseArgs([]string{`-jy`, `--password=x`, `-P`, `y`})
+
+	var errs ParseErrors
+	if !errors.As(err, &errs) || len(errs) != 2 {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestExclusiveUsage(t *testing.T) {
+	t.Parallel()
+
+	tOut := &bytes.Buffer{}
+	p := newParser(stubApp).SetOutput(tOut)
+	p.AddBool("json|j", "JSON output", new(bool), false)
+	p.AddBool("yaml", "YAML output", new(bool), false)
+	p.AddBool("table", "table output", new(bool), false)
+	p.SetExclusive("json", "yaml").SetExclusive("table", "j")
+	p.Usage()
+
+	want := `
+Usage of ` + stubApp + `:
+    --json[=true|false], -j[=true|false]
+      JSON output (mutually exclusive with --yaml, --table, default: false)
+    --yaml[=true|false]
+      YAML output (mutually exclusive with --json, default: false)
+    --table[=true|false]
+      table output (mutually exclusive with --json, default: false)
+`
+	if tOut.String() != want {
+		t.Errorf("incorrect usage output: want -\n%s\ngot -\n%s", want, tOut.String())
+	}
+}
+
+func TestExclusivePanic(t *testing.T) {
+	t.Parallel()
+
+	for testN, groups := range map[string][]string{
+		`single-option`:	{`json`},
+		`unknown-option`:	{`json`, `xml`},
+	} {
+		func() {
+			defer func() {
+				if _, ok := recover().(OptsPanic); !ok {
+					t.Errorf("%q must panic with OptsPanic", testN)
+				}
+			}()
+
+			exclusiveOpts(newTestParser(nil)).SetExclusive(groups...)
+		}()
+	}
+}
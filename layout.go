@@ -0,0 +1,154 @@
+package optsparser
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	envColumns		= "COLUMNS"
+	widthDefault	= 80
+	minDescrWidth	= 20	// minimal width of descriptions in the second column
+)
+
+// SetWidth sets the width of the Usage output, descriptions of options, arguments and commands are
+// word-wrapped to it. If the width is 0, which is the default, it is taken from the COLUMNS environment
+// variable, or 80 columns are used. Words longer than the width are never split. SetWidth panics if
+// the width is negative.
+func (p *OptsParser) SetWidth(width int) *OptsParser {
+	if width < 0 {
+		doPanic("Invalid width of the Usage output: %d", width)
+	}
+
+	p.usageWidth = width
+
+	return p
+}
+
+// SetIndents sets the number of spaces before specifications of options and before their descriptions
+// in the Usage output, by default they are 4 and 6. The optIndent is also used for separators, arguments,
+// commands and the synopsis. SetIndents panics if any of indents is negative.
+func (p *OptsParser) SetIndents(optIndent, helpIndent int) *OptsParser {
+	if optIndent < 0 || helpIndent < 0 {
+		doPanic("Invalid indents of the Usage output: %d, %d", optIndent, helpIndent)
+	}
+
+	p.optIndent = strings.Repeat(" ", optIndent)
+	p.helpIndent = strings.Repeat(" ", helpIndent)
+
+	return p
+}
+
+// SetTwoColumns enables the layout in which descriptions are printed in the second column, aligned
+// after the longest specification of an option, e.g.:
+//  --config-path string, -c string  path to configuration (required option)
+//  --workers int, -w int            number of workers (default: 4)
+//
+// The second column starts at most at the middle of the output, if the specification is longer,
+// the description is printed on the next line in the second column. If the output is too narrow
+// for the second column, the usual layout is used.
+func (p *OptsParser) SetTwoColumns(v bool) *OptsParser {
+	p.twoColumns = v
+
+	return p
+}
+
+// width returns the width of the output, set by SetWidth, from the COLUMNS environment variable, or the default
+func (p *OptsParser) width() int {
+	if p.usageWidth != 0 {
+		return p.usageWidth
+	}
+
+	if cols, ok := p.lookupEnv(envColumns); ok {
+		if w, err := strconv.Atoi(cols); err == nil && w > 0 {
+			return w
+		}
+	}
+
+	return widthDefault
+}
+
+// column returns the position of the second column for the specifications, or 0 if
+// the two-column layout is not used
+func (p *OptsParser) column(specs []string) int {
+	if !p.twoColumns {
+		return 0
+	}
+
+	limit := p.width() / 2
+	if p.width() - limit < minDescrWidth {
+		// Too narrow for two columns
+		return 0
+	}
+
+	col := 0
+	for _, spec := range specs {
+		// Descriptions are separated from specifications by two spaces
+		if w := utf8.RuneCountInString(p.optIndent + spec) + 2; w > col && w <= limit {
+			col = w
+		}
+	}
+	if col == 0 {
+		// All specifications are too long
+		col = limit
+	}
+
+	return col
+}
+
+// descrIndent returns the indent of descriptions
+func (p *OptsParser) descrIndent(col int) string {
+	if col == 0 {
+		return p.helpIndent
+	}
+
+	return strings.Repeat(" ", col)
+}
+
+// layoutEntry returns the specification of an option, an argument or a command followed by the wrapped
+// description, which is placed on the next line or in the second column at col, if it is not 0
+func (p *OptsParser) layoutEntry(spec, descr string, col int) string {
+	head := p.optIndent + spec
+	indent := p.descrIndent(col)
+
+	if col == 0 || utf8.RuneCountInString(head) + 2 > col {
+		// Description starts on the next line
+		return head + "\n" + wrapText(descr, indent, indent, p.width())
+	}
+
+	return wrapText(descr, head + strings.Repeat(" ", col - utf8.RuneCountInString(head)), indent, p.width())
+}
+
+// wrapText word-wraps the text to lines of the width, the first line starts with the first prefix, others
+// with the indent. Explicit line breaks of the text are preserved, empty lines separate paragraphs.
+func wrapText(text, first, indent string, width int) string {
+	out := &strings.Builder{}
+
+	prefix := first
+	for _, para := range strings.Split(text, "\n") {
+		words := strings.Fields(para)
+		if len(words) == 0 {
+			// Empty line between paragraphs
+			out.WriteString(strings.TrimRight(prefix, " ") + "\n")
+			prefix = indent
+
+			continue
+		}
+
+		line := prefix + words[0]
+		for _, word := range words[1:] {
+			if utf8.RuneCountInString(line) + 1 + utf8.RuneCountInString(word) > width {
+				out.WriteString(line + "\n")
+				line = indent + word
+				continue
+			}
+			line += " " + word
+		}
+		out.WriteString(line + "\n")
+
+		prefix = indent
+	}
+
+	return out.String()
+}
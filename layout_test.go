@@ -0,0 +1,201 @@
+package optsparser
+
+import (
+	"bytes"
+	"testing"
+)
+
+// layoutOpts adds options with long descriptions used by tests
+func layoutOpts(p *OptsParser) *OptsParser {
+	p.AddString("config-path|c", "path to the configuration file, which is read before the environment" +
+		" variables and the command line", new(string), "")
+	p.AddInt("workers|w", "number of workers", new(int), 4)
+	p.AddSeparator("Output options, they are applied after the configuration file is loaded and may be" +
+		" overridden by the environment")
+	p.AddChoice("format", "output format", new(string), "text", "text", "json")
+	p.SetChoiceDescr("format", "json", "machine-readable output, one object per line, suitable for jq" +
+		" and other tools")
+	p.AddBool("very-long-option-name-for-testing", "option with a long name", new(bool), false)
+	p.AddString("template", "template of the output.\n\nThe template uses the syntax of the text/template" +
+		" package, fields of records are available by names", new(string), "")
+	AddArg(p, "SRC", "source of the data", new(string))
+
+	return p
+}
+
+func TestLayout(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct{
+		env		map[string]string
+		setup	func(p *OptsParser)
+		want	string
+	}{
+		`columns-env`:	{
+			env:	map[string]string{`COLUMNS`: `60`},
+			want:	`
+Usage of app:
+    app [options] SRC
+    --config-path string, -c string
+      path to the configuration file, which is read before
+      the environment variables and the command line
+      (required option)
+    --workers int, -w int
+      number of workers (default: 4)
+    Output options, they are applied after the configuration
+    file is loaded and may be overridden by the environment
+    --format text|json
+      output format (default: text)
+        json - machine-readable output, one object per line,
+          suitable for jq and other tools
+    --very-long-option-name-for-testing[=true|false]
+      option with a long name (default: false)
+    --template string
+      template of the output.
+
+      The template uses the syntax of the text/template
+      package, fields of records are available by names
+      (default: "")
+
+Arguments:
+    SRC
+      source of the data
+`,
+		},
+		`two-columns`:	{
+			// Width set explicitly has priority over the environment
+			env:	map[string]string{`COLUMNS`: `200`},
+			setup:	func(p *OptsParser) { p.SetWidth(70).SetTwoColumns(true).SetIndents(2, 4) },
+			want:	`
+Usage of app:
+  app [options] SRC
+  --config-path string, -c string  path to the configuration file,
+                                   which is read before the
+                                   environment variables and the
+                                   command line (required option)
+  --workers int, -w int            number of workers (default: 4)
+  Output options, they are applied after the configuration file is
+  loaded and may be overridden by the environment
+  --format text|json               output format (default: text)
+                                     json - machine-readable output,
+                                       one object per line, suitable
+                                       for jq and other tools
+  --very-long-option-name-for-testing[=true|false]
+                                   option with a long name (default:
+                                   false)
+  --template string                template of the output.
+
+                                   The template uses the syntax of the
+                                   text/template package, fields of
+                                   records are available by names
+                                   (default: "")
+
+Arguments:
+  SRC  source of the data
+`,
+		},
+		`too-narrow-for-columns`:	{
+			setup:	func(p *OptsParser) { p.SetWidth(30).SetTwoColumns(true).SetIndents(0, 2) },
+			want:	`
+Usage of app:
+app [options] SRC
+--config-path string, -c string
+  path to the configuration
+  file, which is read before
+  the environment variables
+  and the command line
+  (required option)
+--workers int, -w int
+  number of workers (default:
+  4)
+Output options, they are
+applied after the
+configuration file is loaded
+and may be overridden by the
+environment
+--format text|json
+  output format (default:
+  text)
+    json - machine-readable
+      output, one object per
+      line, suitable for jq
+      and other tools
+--very-long-option-name-for-testing[=true|false]
+  option with a long name
+  (default: false)
+--template string
+  template of the output.
+
+  The template uses the syntax
+  of the text/template
+  package, fields of records
+  are available by names
+  (default: "")
+
+Arguments:
+SRC
+  source of the data
+`,
+		},
+	}
+
+	for testN, test := range tests {
+		p := layoutOpts(newParser("app", "config-path"))
+		p.lookupEnv = testEnv(test.env)
+		if test.setup != nil {
+			test.setup(p)
+		}
+
+		tOut := &bytes.Buffer{}
+		p.WriteUsage(tOut)
+		if tOut.String() != test.want {
+			t.Errorf("%q incorrect usage output: want -\n%s\ngot -\n%s", testN, test.want, tOut.String())
+		}
+	}
+}
+
+func TestLayoutCommands(t *testing.T) {
+	t.Parallel()
+
+	tOut := &bytes.Buffer{}
+	p := newParser(stubApp).SetOutput(tOut).SetWidth(50).SetTwoColumns(true)
+	p.AddBool("verbose|v", "verbose output", new(bool), false)
+	p.AddCommand("serve", "start the server and serve requests until the process is terminated")
+	p.AddCommand("db", "")
+
+	p.WriteUsage(tOut)
+	want := `
+Usage of ` + stubApp + `:
+    --verbose[=true|false], -v[=true|false]
+                         verbose output (default:
+                         false)
+
+Commands:
+    serve  start the server and serve requests
+           until the process is terminated
+    db
+`
+	if tOut.String() != want {
+		t.Errorf("incorrect usage output: want -\n%s\ngot -\n%s", want, tOut.String())
+	}
+}
+
+func TestLayoutPanic(t *testing.T) {
+	t.Parallel()
+
+	for testN, set := range map[string]func(p *OptsParser){
+		`negative-width`:		func(p *OptsParser) { p.SetWidth(-1) },
+		`negative-opt-indent`:	func(p *OptsParser) { p.SetIndents(-1, 2) },
+		`negative-help-indent`:	func(p *OptsParser) { p.SetIndents(2, -2) },
+	} {
+		func() {
+			defer func() {
+				if _, ok := recover().(OptsPanic); !ok {
+					t.Errorf("%q must panic with OptsPanic", testN)
+				}
+			}()
+
+			set(layoutOpts(newParser("app", "config-path")))
+		}()
+	}
+}
@@ -0,0 +1,91 @@
+package optsparser
+
+import (
+	"time"
+)
+
+// AddStrings adds a string slice option with specified option name, usage string and default value.
+// The argument val points to a string slice in which to store the values of the option. The option
+// can be specified several times, each value is appended to the slice, but the first one replaces
+// the default value. Use [OptsParser.SetDelimiter] to allow several values in one argument.
+func (p *OptsParser) AddStrings(optName, usage string, val *[]string, dfltVal []string) {
+	addSlice(p, typeString, optName, usage, val, dfltVal, parseString)
+}
+
+// AddInts adds an int slice option with specified option name, usage string and default value.
+// The argument val points to an int slice in which to store the values of the option.
+// See [OptsParser.AddStrings] for details of how the values are collected.
+func (p *OptsParser) AddInts(optName, usage string, val *[]int, dfltVal []int) {
+	addSlice(p, typeInt, optName, usage, val, dfltVal, parseInt)
+}
+
+// AddInt64s adds an int64 slice option with specified option name, usage string and default value.
+// The argument val points to an int64 slice in which to store the values of the option.
+// See [OptsParser.AddStrings] for details of how the values are collected.
+func (p *OptsParser) AddInt64s(optName, usage string, val *[]int64, dfltVal []int64) {
+	addSlice(p, typeInt64, optName, usage, val, dfltVal, parseInt64)
+}
+
+// AddUints adds a uint slice option with specified option name, usage string and default value.
+// The argument val points to a uint slice in which to store the values of the option.
+// See [OptsParser.AddStrings] for details of how the values are collected.
+func (p *OptsParser) AddUints(optName, usage string, val *[]uint, dfltVal []uint) {
+	addSlice(p, typeUint, optName, usage, val, dfltVal, parseUint)
+}
+
+// AddUint64s adds a uint64 slice option with specified option name, usage string and default value.
+// The argument val points to a uint64 slice in which to store the values of the option.
+// See [OptsParser.AddStrings] for details of how the values are collected.
+func (p *OptsParser) AddUint64s(optName, usage string, val *[]uint64, dfltVal []uint64) {
+	addSlice(p, typeUint64, optName, usage, val, dfltVal, parseUint64)
+}
+
+// AddFloat64s adds a float64 slice option with specified option name, usage string and default value.
+// The argument val points to a float64 slice in which to store the values of the option.
+// See [OptsParser.AddStrings] for details of how the values are collected.
+func (p *OptsParser) AddFloat64s(optName, usage string, val *[]float64, dfltVal []float64) {
+	addSlice(p, typeFloat64, optName, usage, val, dfltVal, parseFloat64)
+}
+
+// AddDurations adds a [time.Duration] slice option with specified option name, usage string and
+// default value. The argument val points to a [time.Duration] slice in which to store the values
+// of the option. See [OptsParser.AddStrings] for details of how the values are collected.
+func (p *OptsParser) AddDurations(optName, usage string, val *[]time.Duration, dfltVal []time.Duration) {
+	addSlice(p, typeDuration, optName, usage, val, dfltVal, parseDuration)
+}
+
+// SetDelimiter sets the delimiter of values of the multi-value option optName, e.g. with
+// the delimiter "," the argument "--tags a,b,c" is the same as "--tags a --tags b --tags c".
+// By default, slice options have no delimiter. The optName can be either long or short name
+// of the option. SetDelimiter panics if the option was not added or does not support multiple values.
+func (p *OptsParser) SetDelimiter(optName, delim string) *OptsParser {
+	f := p.Lookup(optName)
+	if f == nil {
+		doPanic("Cannot set delimiter for option %q - option was not added", optName)
+	}
+
+	mv, ok := f.Value.(multiValue)
+	if !ok {
+		doPanic("Cannot set delimiter for option %q - option does not accept multiple values", optName)
+	}
+
+	mv.setDelimiter(delim)
+
+	return p
+}
+
+func addSlice[T any](p *OptsParser, optType, optName, usage string, val *[]T, dfltVal []T,
+		parse func(string) (T, error)) {
+	// Set default value
+	*val = append([]T{}, dfltVal...)
+
+	long, short, shOk := p.parseOptName(optType, optName, usage)
+
+	// The same value is used by both forms of the option to
+	// correctly replace the default value by the first one
+	sv := &sliceValue[T]{val: val, parse: parse, format: formatAny[T]}
+	p.Var(sv, long, usage)
+	if shOk {
+		p.Var(sv, short, usage)
+	}
+}
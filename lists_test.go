@@ -0,0 +1,165 @@
+package optsparser
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testListOpts struct {
+	strings		[]string
+	ints		[]int
+	int64s		[]int64
+	uints		[]uint
+	uint64s		[]uint64
+	float64s	[]float64
+	durations	[]time.Duration
+}
+
+func newListParser(to *testListOpts) *OptsParser {
+	p := newParser(stubApp).SetUsageOnFail(false).SetOutput(&bytes.Buffer{})
+
+	p.AddStrings("include|I", "include directory", &to.strings, []string{"/usr/include"})
+	p.AddInts("int|i", "int values", &to.ints, nil)
+	p.AddInt64s("int64", "int64 values", &to.int64s, []int64{-1})
+	p.AddUints("uint", "uint values", &to.uints, nil)
+	p.AddUint64s("uint64", "uint64 values", &to.uint64s, nil)
+	p.AddFloat64s("float64", "float64 values", &to.float64s, nil)
+	p.AddDurations("duration|d", "duration values", &to.durations, []time.Duration{time.Second})
+
+	p.SetDelimiter("i", ",").
+		SetDelimiter("int64", ",").
+		SetDelimiter("uint", ":").
+		SetDelimiter("float64", ",").
+		SetDelimiter("duration", ",")
+
+	return p
+}
+
+func TestLists(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct{
+		args	[]string
+		want	testListOpts
+	}{
+		`defaults`:	{
+			args:	[]string{},
+			want:	testListOpts{
+				strings:	[]string{"/usr/include"},
+				ints:		[]int{},
+				int64s:		[]int64{-1},
+				uints:		[]uint{},
+				uint64s:	[]uint64{},
+				float64s:	[]float64{},
+				durations:	[]time.Duration{time.Second},
+			},
+		},
+		`repeat-and-delimiters`:	{
+			args:	[]string{
+				`-I`, `a,b`, `--include`, `c`, `-Id`,
+				`--int`, `1,2`, `-i3`,
+				`--int64`, `-10,0x10`,
+				`--uint`, `1:2:3`,
+				`--uint64`, `18446744073709551615`, `--uint64`, `0`,
+				`--float64`, `0.5,1e3`,
+				`-d`, `1m,2s`, `--duration`, `1h`,
+			},
+			want:	testListOpts{
+				strings:	[]string{`a,b`, `c`, `d`},
+				ints:		[]int{1, 2, 3},
+				int64s:		[]int64{-10, 16},
+				uints:		[]uint{1, 2, 3},
+				uint64s:	[]uint64{18446744073709551615, 0},
+				float64s:	[]float64{0.5, 1000},
+				durations:	[]time.Duration{time.Minute, 2 * time.Second, time.Hour},
+			},
+		},
+	}
+
+	for testN, test := range tests {
+		testN, test := testN, test
+		t.Run(testN, func(t *testing.T) {
+			t.Parallel()
+
+			to := testListOpts{}
+			p := newListParser(&to)
+
+			if err := p.ParseArgs(test.args); err != nil {
+				t.Errorf("%q parse failed: %v", testN, err)
+				return
+			}
+
+			if !reflect.DeepEqual(to, test.want) {
+				t.Errorf("%q incorrect Parse result: want - %#v got - %#v", testN, test.want, to)
+			}
+		})
+	}
+}
+
+func TestListsFail(t *testing.T) {
+	t.Parallel()
+
+	for args, want := range map[string]string{
+		`--int=1,x`:			`invalid value "1,x" for flag --int: parse error`,
+		`--int64=1,,2`:			`invalid value "1,,2" for flag --int64: parse error`,
+		`--uint=-1`:			`invalid value "-1" for flag --uint: parse error`,
+		`--uint64=1,2`:			`invalid value "1,2" for flag --uint64: parse error`,
+		`--float64=1,x`:		`invalid value "1,x" for flag --float64: parse error`,
+		`-d1y`:					`invalid value "1y" for flag -d: parse error`,
+	} {
+		p := newListParser(&testListOpts{})
+
+		if err := p.ParseArgs([]string{args}); err == nil || err.Error() != want {
+			t.Errorf("parse of %q returned unexpected error: want - %q got - %v", args, want, err)
+		}
+	}
+}
+
+func TestListsUsage(t *testing.T) {
+	t.Parallel()
+
+	tOut := &bytes.Buffer{}
+	p := newParser(stubApp).SetOutput(tOut)
+	p.AddStrings("include|I", "include directory", new([]string), []string{"/usr/include", "/opt/include"})
+	p.AddInts("port", "ports to listen", new([]int), nil)
+	p.AddDurations("d", "delays", new([]time.Duration), []time.Duration{time.Second})
+	p.SetDelimiter("port", ",")
+	p.Usage()
+
+	want := `
+Usage of ` + stubApp + `:
+    --include string, -I string
+      include directory (can be repeated, default: [/usr/include, /opt/include])
+    --port int[,...]
+      ports to listen (can be repeated, default: [])
+    -d duration
+      delays (can be repeated, default: [1s])
+`
+	if tOut.String() != want {
+		t.Errorf("output produced by Usage is different from expexted, see below:\n" +
+			"\n-------- Want --------\n%s\n" +
+			"-------- Got --------\n%s\n",
+			want, tOut.String(),
+		)
+	}
+}
+
+func TestSetDelimiterPanic(t *testing.T) {
+	t.Parallel()
+
+	for _, name := range []string{`unknown`, `string-opt`} {
+		func() {
+			defer func() {
+				if _, ok := recover().(OptsPanic); !ok {
+					t.Errorf("SetDelimiter(%q) did not cause a panic, but it must", name)
+				}
+			}()
+
+			p := newParser(stubApp)
+			p.AddString("string-opt", "string value", new(string), "")
+			p.SetDelimiter(name, ",")
+		}()
+	}
+}
@@ -0,0 +1,75 @@
+package optsparser
+
+import (
+	"time"
+)
+
+// Scalar is the set of types of values supported by generic functions of the package
+type Scalar interface {
+	string | bool | int | int64 | uint | uint64 | float64 | time.Duration
+}
+
+// DupKeyPolicy defines how map options handle keys that are specified several times
+type DupKeyPolicy int
+
+const (
+	// DupKeyLast replaces the previous value of the key, it is the default policy
+	DupKeyLast DupKeyPolicy = iota
+	// DupKeyFirst keeps the first value of the key, subsequent values are ignored
+	DupKeyFirst
+	// DupKeyError causes the parsing error
+	DupKeyError
+)
+
+// AddStringMap adds an option that accepts key=value pairs, with specified option name, usage string
+// and default value. The argument val points to a map in which to store the pairs. The pairs can be
+// specified by repeating the option or in one argument separated by commas, e.g. "--label env=prod
+// --label team=core" is the same as "--label env=prod,team=core". The first explicit value replaces
+// the default value. The delimiter can be changed by [OptsParser.SetDelimiter], handling of duplicate
+// keys can be configured by [OptsParser.SetDupKeyPolicy].
+func (p *OptsParser) AddStringMap(optName, usage string, val *map[string]string, dfltVal map[string]string) {
+	AddMap(p, optName, usage, val, dfltVal)
+}
+
+// AddMap works like [OptsParser.AddStringMap], but values of pairs are converted to the type T.
+// For example, the following code allows options like "--limit cpu=2,mem=4":
+//  var limits map[string]int
+//  optsparser.AddMap(p, "limit", "resource limits", &limits, nil)
+func AddMap[T Scalar](p *OptsParser, optName, usage string, val *map[string]T, dfltVal map[string]T) {
+	parse, typeName := scalarParser[T]()
+
+	// Set default value
+	*val = make(map[string]T, len(dfltVal))
+	for k, v := range dfltVal {
+		(*val)[k] = v
+	}
+
+	long, short, shOk := p.parseOptName("key=" + typeName, optName, usage)
+
+	// The same value is used by both forms of the option to
+	// correctly replace the default value by the first one
+	mv := &mapValue[T]{val: val, parse: parse, format: formatAny[T], delim: mapDelimDefault}
+	p.Var(mv, long, usage)
+	if shOk {
+		p.Var(mv, short, usage)
+	}
+}
+
+// SetDupKeyPolicy sets the policy of handling duplicate keys of the map option optName, by default
+// [DupKeyLast] is used. The optName can be either long or short name of the option. SetDupKeyPolicy
+// panics if the option was not added or it is not a map option.
+func (p *OptsParser) SetDupKeyPolicy(optName string, policy DupKeyPolicy) *OptsParser {
+	f := p.Lookup(optName)
+	if f == nil {
+		doPanic("Cannot set duplicate keys policy for option %q - option was not added", optName)
+	}
+
+	dv, ok := f.Value.(interface{ setDupKeyPolicy(DupKeyPolicy) })
+	if !ok {
+		doPanic("Cannot set duplicate keys policy for option %q - option is not a map", optName)
+	}
+
+	dv.setDupKeyPolicy(policy)
+
+	return p
+}
@@ -0,0 +1,148 @@
+package optsparser
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMaps(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct{
+		args		[]string
+		policy		DupKeyPolicy
+		wantLabels	map[string]string
+		wantLimits	map[string]int
+	}{
+		`defaults`:	{
+			args:		[]string{},
+			wantLabels:	map[string]string{`env`: `dev`},
+			wantLimits:	map[string]int{},
+		},
+		`repeat-and-delimiter`:	{
+			args:		[]string{`--label`, `env=prod`, `-l`, `team=core,empty=,eq=a=b`, `--limit`, `cpu=2`},
+			wantLabels:	map[string]string{`env`: `prod`, `team`: `core`, `empty`: ``, `eq`: `a=b`},
+			wantLimits:	map[string]int{`cpu`: 2},
+		},
+		`duplicate-last`:	{
+			args:		[]string{`--label`, `env=prod`, `-l`, `env=test`},
+			wantLabels:	map[string]string{`env`: `test`},
+			wantLimits:	map[string]int{},
+		},
+		`duplicate-first`:	{
+			args:		[]string{`--label`, `env=prod`, `-l`, `env=test`},
+			policy:		DupKeyFirst,
+			wantLabels:	map[string]string{`env`: `prod`},
+			wantLimits:	map[string]int{},
+		},
+	}
+
+	for testN, test := range tests {
+		testN, test := testN, test
+		t.Run(testN, func(t *testing.T) {
+			t.Parallel()
+
+			var labels map[string]string
+			var limits map[string]int
+
+			p := newParser(stubApp).SetUsageOnFail(false).SetOutput(&bytes.Buffer{})
+			p.AddStringMap("label|l", "labels", &labels, map[string]string{`env`: `dev`})
+			AddMap(p, "limit", "limits", &limits, nil)
+			p.SetDupKeyPolicy("l", test.policy)
+
+			if err := p.ParseArgs(test.args); err != nil {
+				t.Errorf("%q parse failed: %v", testN, err)
+				return
+			}
+
+			if !reflect.DeepEqual(labels, test.wantLabels) {
+				t.Errorf("%q incorrect labels: want - %#v got - %#v", testN, test.wantLabels, labels)
+			}
+			if !reflect.DeepEqual(limits, test.wantLimits) {
+				t.Errorf("%q incorrect limits: want - %#v got - %#v", testN, test.wantLimits, limits)
+			}
+		})
+	}
+}
+
+func TestMapsFail(t *testing.T) {
+	t.Parallel()
+
+	for args, want := range map[string]string{
+		`--label=env`:			`invalid value "env" for flag --label: malformed pair "env", want key=value`,
+		`--label=a=1,=2`:		`invalid value "a=1,=2" for flag --label: malformed pair "=2", want key=value`,
+		`--label=a=1,a=2`:		`invalid value "a=1,a=2" for flag --label: duplicate key "a"`,
+		`--limit=cpu=x`:		`invalid value "cpu=x" for flag --limit: invalid value of key "cpu": parse error`,
+		`--timeout=x=1y`:		`invalid value "x=1y" for flag --timeout: invalid value of key "x": parse error`,
+		`--flag=x=yes`:			`invalid value "x=yes" for flag --flag: invalid value of key "x": parse error`,
+	} {
+		p := newParser(stubApp).SetUsageOnFail(false).SetOutput(&bytes.Buffer{})
+		p.AddStringMap("label|l", "labels", new(map[string]string), nil)
+		p.SetDupKeyPolicy("label", DupKeyError)
+		AddMap(p, "limit", "limits", new(map[string]uint64), nil)
+		AddMap(p, "timeout", "timeouts", new(map[string]time.Duration), nil)
+		AddMap(p, "flag", "flags", new(map[string]bool), nil)
+
+		if err := p.ParseArgs([]string{args}); err == nil || err.Error() != want {
+			t.Errorf("parse of %q returned unexpected error: want - %q got - %v", args, want, err)
+		}
+	}
+
+	// Value with a duplicate key does not change the map
+	labels := map[string]string{}
+	p := newParser(stubApp).SetUsageOnFail(false).SetOutput(&bytes.Buffer{})
+	p.AddStringMap("label|l", "labels", &labels, nil)
+	p.SetDupKeyPolicy("label", DupKeyError)
+	if err := p.ParseArgs([]string{`-l`, `x=0`, `-l`, `a=1,b=2,a=3`}); err == nil {
+		t.Errorf("parse of duplicate key must fail")
+	}
+	if want := map[string]string{`x`: `0`}; !reflect.DeepEqual(labels, want) {
+		t.Errorf("map is changed by invalid value: want - %v got - %v", want, labels)
+	}
+}
+
+func TestMapsUsage(t *testing.T) {
+	t.Parallel()
+
+	tOut := &bytes.Buffer{}
+	p := newParser(stubApp).SetOutput(tOut)
+	p.AddStringMap("label|l", "labels", new(map[string]string), map[string]string{`team`: `core`, `env`: `dev`})
+	AddMap(p, "weight", "weights", new(map[string]float64), nil)
+	p.SetDelimiter("weight", "")
+	p.Usage()
+
+	want := `
+Usage of ` + stubApp + `:
+    --label key=string[,...], -l key=string[,...]
+      labels (can be repeated, default: {env=dev, team=core})
+    --weight key=float64
+      weights (can be repeated, default: {})
+`
+	if tOut.String() != want {
+		t.Errorf("output produced by Usage is different from expexted, see below:\n" +
+			"\n-------- Want --------\n%s\n" +
+			"-------- Got --------\n%s\n",
+			want, tOut.String(),
+		)
+	}
+}
+
+func TestSetDupKeyPolicyPanic(t *testing.T) {
+	t.Parallel()
+
+	for _, name := range []string{`unknown`, `list`} {
+		func() {
+			defer func() {
+				if _, ok := recover().(OptsPanic); !ok {
+					t.Errorf("SetDupKeyPolicy(%q) did not cause a panic, but it must", name)
+				}
+			}()
+
+			p := newParser(stubApp)
+			p.AddStrings("list", "string values", new([]string), nil)
+			p.SetDupKeyPolicy(name, DupKeyError)
+		}()
+	}
+}
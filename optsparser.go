@@ -1,16 +1,28 @@
 package optsparser
 import (
 	"bytes"
+	"encoding"
 	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"sort"
 	"strings"
 	"time"
 )
 
-const lsJoinDefault = ", "
+const (
+	lsJoinDefault		= ", "
+	negPrefixDefault	= "no-"
+)
+
+const (
+	// ExitCodeHelp is the exit code used when the help was requested by the user
+	ExitCodeHelp	= 0
+	// ExitCodeError is the exit code used when the parsing failed
+	ExitCodeError	= 2
+)
 
 type OptsParser struct {
 	flag.FlagSet
@@ -23,11 +35,40 @@ type OptsParser struct {
 	lsJoinStr		string	// long + short join string
 	shortFirst		bool
 	usageOnFail		bool
+	strictDashes	bool
+	interspersed	bool
+	negPrefix		string
+	envPrefix		string
+	configOpt		string
+	sources			map[string]Source
+	rejected		map[string]bool	// options marked as set by the FlagSet only by rejected values
+	explain			bool
+	explainOpt		string
+	noRespFiles		bool
+	respFilePrefix	rune
+	reportAll		bool
+	errs			[]error	// errors collected in the report all mode
+	lookupEnv		func(string) (string, bool)
+	parent			*OptsParser
+	commands		map[string]*OptsParser
+	cmdOrder		[]string
+	cmdDescr		string
+	run				func(*OptsParser) error
+	selected		*OptsParser	// command selected by the last parsing
+	outputSet		bool		// output was set by SetOutput
+	exitFunc		func(int)
+	exclusive		[][]string	// groups of mutually exclusive options
+	constraints		[]constraint
+	args			[]*argDescr	// declared positional arguments
+	autoSynopsis	bool
+	usageWidth		int		// width of the Usage output, 0 if it is detected
+	optIndent		string
+	helpIndent		string
+	twoColumns		bool
 	//
 	// Variables required for testing
 	//
 	usageTriggered	bool	// show that Usage() was triggered
-	usageNotExits	bool	// if true - Usage does not call os.Exit()
 }
 
 // NewParser returns a new options parser with the specified name and set of required
@@ -40,8 +81,16 @@ func NewParser(name string, required ...string) *OptsParser {
 		longOpts:		map[string]*optDescr{},
 		orderedList:	[]string{},
 		required:		map[string]bool{},
+		sources:		map[string]Source{},
+		rejected:		map[string]bool{},
 		lsJoinStr:		lsJoinDefault,
+		negPrefix:		negPrefixDefault,
+		respFilePrefix:	respFilePrefixDefault,
 		usageOnFail:	true,
+		lookupEnv:		os.LookupEnv,
+		exitFunc:		os.Exit,
+		optIndent:		optIndentDefault,
+		helpIndent:		helpIndentDefault,
 	}
 
 	// Set stub to FlagSet.Usage to suppress default output
@@ -59,6 +108,8 @@ func NewParser(name string, required ...string) *OptsParser {
 // that uses optsparser. It will be printed by the Usage function after the error message (if any),
 // but before reference to supported options. For example:
 //  p.SetGeneralDescr("$ my-app-name --required-keys ... [--optional-keys ...]")
+//
+// The general form of the command can be generated from options, see [OptsParser.SetAutoSynopsis].
 func (p *OptsParser) SetGeneralDescr(descr string) *OptsParser {
 	p.generalDescr = descr
 
@@ -75,6 +126,17 @@ func (p *OptsParser) AddBool(optName, usage string, val *bool, dfltVal bool) {
 	}
 }
 
+// AddNegatableBool works like [OptsParser.AddBool], but in addition to the "--long-name" form,
+// the option can be set to false using the "--no-long-name" form. The negation prefix can be
+// changed using [OptsParser.SetNegationPrefix]. In the Usage output the option is printed
+// as "--[no-]long-name". Only the long form of the option can be negated.
+func (p *OptsParser) AddNegatableBool(optName, usage string, val *bool, dfltVal bool) {
+	p.AddBool(optName, usage, val, dfltVal)
+
+	long, _, _ := strings.Cut(optName, "|")
+	p.longOpts[long].negatable = true
+}
+
 // AddString adds a string option with specified option name, usage string and default value.
 // The argument val points to a string variable in which to store the value of the option.
 func (p *OptsParser) AddString(optName, usage string, val *string, dfltVal string) {
@@ -155,6 +217,73 @@ func (p *OptsParser) AddVar(optName, usage string, val flag.Value) {
 	}
 }
 
+// AddTextVar adds an option with specified option name, usage string and default value, the value
+// of the option is parsed by the UnmarshalText method of val. It is suitable for many types from
+// the standard library, like [net.IP], [net/netip.Prefix], [math/big.Int], [time.Time] or [log/slog.Level].
+// If dfltVal is not nil, its text representation is used as the default value. The valName
+// is used as the name of the value in the Usage output, if it is empty "value" is used.
+//
+// AddTextVar panics if the default value cannot be unmarshaled by val.
+func (p *OptsParser) AddTextVar(optName, usage string, val encoding.TextUnmarshaler, dfltVal encoding.TextMarshaler,
+		valName string) {
+	// Set default value
+	if dfltVal != nil {
+		text, err := dfltVal.MarshalText()
+		if err == nil {
+			err = val.UnmarshalText(text)
+		}
+		if err != nil {
+			doPanic("Invalid default value of option %q: %v", optName, err)
+		}
+	}
+
+	p.addValue(optName, usage, &textValue{val: val}, valName)
+}
+
+// AddFunc adds an option with specified option name and usage string, each value of the option
+// is passed to the fn function. If fn returns an error, the parsing fails. The valName is used as
+// the name of the value in the Usage output, if it is empty "value" is used.
+func (p *OptsParser) AddFunc(optName, usage string, fn func(string) error, valName string) {
+	p.addValue(optName, usage, funcValue(fn), valName)
+}
+
+func (p *OptsParser) addValue(optName, usage string, val flag.Value, valName string) {
+	if valName == "" {
+		valName = typeVal
+	}
+
+	long, short, shOk := p.parseOptName(valName, optName, usage)
+	p.Var(val, long, usage)
+	if shOk {
+		p.Var(val, short, usage)
+	}
+}
+
+// AddCounter adds an int option that counts its occurrences in the command line, e.g. "-vvv" or
+// "--verbose --verbose --verbose" increase the value by 3. The counter option does not require a value,
+// but the explicit value "--verbose=N" is treated as N occurrences of the option. The argument val
+// points to an int variable in which to store the value of the option, it is set to dfltVal.
+func (p *OptsParser) AddCounter(optName, usage string, val *int, dfltVal int) {
+	*val = dfltVal
+	p.addCounter(optName, usage, val, 1)
+}
+
+// AddDecCounter adds an int option that decreases the counter by each of its occurrences.
+// Normally, it is used in pair with an option added by [OptsParser.AddCounter] to the same
+// variable, e.g. "--verbose|v" and "--quiet|q" options. Unlike AddCounter, it does not change
+// the current value of the variable pointed by val.
+func (p *OptsParser) AddDecCounter(optName, usage string, val *int) {
+	p.addCounter(optName, usage, val, -1)
+}
+
+func (p *OptsParser) addCounter(optName, usage string, val *int, step int) {
+	long, short, shOk := p.parseOptName(typeCounter, optName, usage)
+	p.Var(&counterValue{val: val, step: step}, long, usage)
+	if shOk {
+		p.Var(&counterValue{val: val, step: step}, short, usage)
+	}
+}
+
 // AddSeparator adds separation lines between the option references in the Usage function output.
 // Separation strings can be used to group options logically and create a description of a group.
 func (p *OptsParser) AddSeparator(separators ...string) {
@@ -169,8 +298,8 @@ func (p *OptsParser) AddSeparator(separators ...string) {
 	}
 }
 
-// Parse parses command-line options. If an error occurs during parsing-an unknown option,
-// an invalid value, or a required option is missing - [OptsParser.Usage] function is called
+// Parse parses command-line options from os.Args[1:]. If an error occurs during parsing-an unknown
+// option, an invalid value, or a required option is missing - [OptsParser.Usage] function is called
 // by default, to show the error message, provide help, and terminate the program. The Usage call
 // can be disabled by calling [OptsParser.SetUsageOnFail] before Parse with the value false.
 // Then Parse will return a parsing error to the caller.
@@ -178,6 +307,19 @@ func (p *OptsParser) AddSeparator(separators ...string) {
 // Parse panics if any of the required options specified in the [NewParser] call was not defined
 // using the Add* function, or if the format of the option name is incorrect.
 func (p *OptsParser) Parse() error {
+	return p.ParseArgs(os.Args[1:])
+}
+
+// ParseArgs works like [OptsParser.Parse], but parses the options from the args list instead
+// of os.Args. The args list must not include the program name. ParseArgs is useful if the options
+// are not taken from the command line, e.g. in tests or when the arguments list is generated
+// by the application itself.
+func (p *OptsParser) ParseArgs(args []string) error {
+	return p.parse(args, nil)
+}
+
+// parse parses the args list, origins of arguments are set if the list is already expanded by the parent command
+func (p *OptsParser) parse(args []string, origins []argOrigin) error {
 	// Check for all required options was set by Add...() functions
 	for opt, required := range p.required {
 		if required {
@@ -185,45 +327,160 @@ func (p *OptsParser) Parse() error {
 		}
 	}
 
-	//
-	// Suppress parser's output to avoid duplicate error messages
-	//
+	// Do parsing
+	origins, err := p.parseArgs(args, origins)
+	if err != nil {
+		return p.fail(err)
+	}
 
-	// Get current output from parser
-	out := p.FlagSet.Output()
-	// Redirect output to stub buffer
-	p.FlagSet.SetOutput(&bytes.Buffer{})
+	// Were errors collected in the report all mode?
+	if len(p.errs) != 0 {
+		// Options of the parser with commands can be set after the command
+		// name, the command is not parsed, so they cannot be checked
+		if len(p.commands) == 0 {
+			p.errs = append(p.errs, p.checkErrors()...)
+		}
 
-	// Do parsing
-	err := p.FlagSet.Parse(os.Args[1:])
+		return p.fail(ParseErrors(p.errs))
+	}
 
-	// Recover the output to allow Usage to print if an error occurs
-	// XXX Do not use defer for this call because output
-	// XXX has to be recovered BEFORE calling Usage()
-	p.FlagSet.SetOutput(out)
+	// Pass the rest of arguments to the command, if any
+	cmd, err := p.dispatch(origins)
+	if cmd != nil {
+		if err != nil {
+			// Error is already reported by the command
+			return err
+		}
 
+		// Global options can be set after the command name, so check them only now
+		return p.checkOptions()
+	}
 	if err != nil {
-		// Need to show help?
-		if errors.Is(err, flag.ErrHelp) {
-			// Show usage and exit
-			p.Usage()
+		return p.fail(err)
+	}
+
+	// Need to explain where the values of options came from?
+	if p.explainRequested() {
+		// Implied values are effective values too, errors are reported if the program is not exited
+		for _, q := range p.chain() {
+			q.applyImplies()
 		}
+		p.explainConfig()
+		p.exit(0)
+	}
+
+	// Check required options and relations between options
+	return p.checkOptions()
+}
 
-		// Some parsing error, check for need to call Usage on fail
-		if p.usageOnFail {
-			// Call Usage with the error description
-			p.Usage(err)
+// fail reports the parsing error using Usage, if required, and returns it
+func (p *OptsParser) fail(err error) error {
+	// Need to show help?
+	if errors.Is(err, flag.ErrHelp) {
+		// Show usage and exit, help is not an error to report
+		p.Usage()
+
+		return err
+	}
+
+	// Some parsing error, check for need to call Usage on fail
+	if p.usageOnFail {
+		// Call Usage with the error description
+		p.Usage(err)
+	}
+
+	// Just return error
+	return err
+}
+
+// parseArgs sets values of options and returns origins of positional arguments
+func (p *OptsParser) parseArgs(args []string, origins []argOrigin) ([]argOrigin, error) {
+	p.errs = nil
+
+	// Replace response files by their content, unless it was done by the parent command
+	if origins == nil {
+		var err error
+		if args, origins, err = p.expandArgs(args); err != nil {
+			return nil, err
 		}
+	}
+
+	// Split arguments to options and positional arguments
+	assigns, positional, err := p.tokenize(args, origins)
+	if err != nil {
+		return nil, err
+	}
+
+	// Set values of options from the configuration file, its path
+	// can be also set by global options after the command name
+	if err := p.applyConfig(append(assigns, p.cmdAssigns(positional)...)); err != nil {
+		return nil, err
+	}
 
-		// Just return error
-		return err	//nolint:wrapcheck // Obvious parse error - no need to additional error wrapping
+	// Set values of options from the environment
+	if err := p.applyEnv(); err != nil {
+		return nil, err
 	}
 
-	// Check required options
-	return p.checkRequired()
+	// Set values of options from the command line
+	for _, opt := range assigns {
+		origin := origins[opt.idx]
+		src := Source{Kind: SourceArgs, ArgIndex: origin.idx, File: origin.file, Line: origin.line}
+		if err := opt.owner.setOpt(opt.name, opt.value, src); err != nil {
+			err = origin.locate(&InvalidValueError{Option: opt.name, Value: opt.value, Cause: err, Source: src, dash: opt.dash})
+			if err := p.report(err); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// Pass positional arguments to the FlagSet to make them available by the
+	// Args/NArg/Arg methods, the terminator prevents any further options processing
+	if err := p.FlagSet.Parse(append([]string{"--"}, positional...)); err != nil {
+		return nil, err	//nolint:wrapcheck // cannot fail
+	}
+
+	// Positional arguments are the tail of the arguments list, it is not true only
+	// in the interspersed mode, but it is never used by parsers with commands
+	return origins[len(args)-len(positional):], nil
+}
+
+// checkOptions checks required options, relations between options and positional arguments after parsing
+func (p *OptsParser) checkOptions() error {
+	errs := p.checkErrors()
+	if len(errs) == 0 {
+		// OK, return no errors
+		return nil
+	}
+
+	// All errors are reported as the list in the report all mode
+	if p.reportAll {
+		return p.fail(ParseErrors(errs))
+	}
+
+	return p.fail(errs[0])
+}
+
+// checkErrors returns errors of options, then binds positional arguments and returns their errors
+func (p *OptsParser) checkErrors() []error {
+	return append(p.optionsErrors(), p.argsErrors()...)
 }
 
-func (p *OptsParser) checkRequired() error {
+// optionsErrors sets implied options, then returns errors of required options and relations between options
+func (p *OptsParser) optionsErrors() []error {
+	// Implied options can satisfy other constraints, so they are set first
+	errs := p.applyImplies()
+
+	if err := p.missingRequired(); err != nil {
+		errs = append(errs, err)
+	}
+	errs = append(errs, p.exclusiveErrors()...)
+
+	return append(errs, p.constraintsErrors()...)
+}
+
+// missingRequired returns the error if some of required options were not set
+func (p *OptsParser) missingRequired() error {
 	// Check for all required options were set
 	rqSet := p.requiredSet()
 	if len(rqSet) == len(p.required) {
@@ -244,26 +501,17 @@ func (p *OptsParser) checkRequired() error {
 	sort.Strings(opts)
 
 	// List of required options that were not set
-	notSet := make([]string, 0, len(p.required))
+	notSet := make([]OptionName, 0, len(p.required))
 	for _, opt := range opts {
-		if !rqSet[opt] {
-			// Required option can be long or short, to print correct
-			// number of dashes before the option name use dashes() function
-			notSet = append(notSet, dashes(opt) + opt)
+		if rqSet[opt] {
+			continue
 		}
-	}
 
-	// Create an error
-	err := fmt.Errorf("required option(s) is missing: %s", strings.Join(notSet, `, `))
-
-	// Need to call Usage on fail?
-	if p.usageOnFail {
-		// Call Usage with error description
-		p.Usage(err)
+		// Required option can be long or short
+		notSet = append(notSet, p.optionName(opt))
 	}
 
-	// Otherwise - return error
-	return err
+	return &MissingRequiredError{Options: notSet}
 }
 
 func (p *OptsParser) parseOptName(optType, optName, usage string) (string, string, bool) {
@@ -316,15 +564,18 @@ func (p *OptsParser) requiredSet() (map[string]bool) {
 	rqSet := make(map[string]bool, len(p.required))
 
 	p.Visit(func(f *flag.Flag) {
+		// Skip options which values were rejected by validators
+		if p.rejected[p.longName(f.Name)] {
+			return
+		}
+
 		// Treat option name as long name
 		if _, ok := p.required[f.Name]; ok {
-			p.required[f.Name] = true
 			// Save this option to map of set options
 			rqSet[f.Name] = true
 		} else
 		// Threat option name as short name
 		if _, ok := p.required[p.shToLong[f.Name]]; ok {
-			p.required[p.shToLong[f.Name]] = true
 			// Save this option to map of set options
 			rqSet[p.shToLong[f.Name]] = true
 		}
@@ -333,46 +584,90 @@ func (p *OptsParser) requiredSet() (map[string]bool) {
 	return rqSet
 }
 
-func (p *OptsParser) descrLongOpt(optFlag *flag.Flag) string {
-	// Output buffer
-	out := bytes.NewBuffer([]byte{})
+// optSpec returns the specification of the option, e.g. "--workers int, -w int"
+func (p *OptsParser) optSpec(optFlag *flag.Flag) string {
 	// Get long option description
 	descr := p.longOpts[optFlag.Name]
 
 	// Value description function
 	valDescr := func() string {
-		if descr.optType == typeBool {
+		switch {
+		case descr.negatable, descr.optType == typeCounter:
+			// Negatable boolean option or counter, the value is defined by the option name
+			return ""
+		case descr.optType == typeBool:
 			// Boolean option
 			return "[=true|false]"
 		}
+		// Can option accept multiple values in the single argument?
+		if mv, ok := optFlag.Value.(multiValue); ok && mv.delimiter() != "" {
+			return fmt.Sprintf(" %s[%s...]", descr.optType, mv.delimiter())
+		}
 		// Option with non-boolean argument
 		return fmt.Sprintf(" %s", descr.optType)
 	}
 
+	// Prefix of negatable long option
+	negation := ""
+	if descr.negatable {
+		negation = "[" + p.negPrefix + "]"
+	}
+
 	// Is short option exists?
 	if short := descr.short; short != "" {
 		if p.shortFirst {
 			// Print short, join string, then long
-			fmt.Fprintf(out, optIndent + "-%s%s" + "%s" + "--%s%s\n",
-				short, valDescr(), p.lsJoinStr, optFlag.Name, valDescr())
-		} else {
-			// Print long, join string, then short
-			fmt.Fprintf(out, optIndent + "--%s%s" + "%s" + "-%s%s\n",
-				optFlag.Name, valDescr(), p.lsJoinStr, short, valDescr())
+			return fmt.Sprintf("-%s%s" + "%s" + "--%s%s%s",
+				short, valDescr(), p.lsJoinStr, negation, optFlag.Name, valDescr())
 		}
-	} else {
-		// Print only long option name, in fact - long options may be short if only short
-		// option was added by p.Add... function, for such case use dashes() function
-		// to print correct number of dashes before the option
-		fmt.Fprintf(out, optIndent + "%s%s%s\n", dashes(optFlag.Name), optFlag.Name, valDescr())
+		// Print long, join string, then short
+		return fmt.Sprintf("--%s%s%s" + "%s" + "-%s%s",
+			negation, optFlag.Name, valDescr(), p.lsJoinStr, short, valDescr())
 	}
 
-	// Print usage information
-	out.WriteString(helpIndent + optFlag.Usage)
+	if descr.negatable && len(optFlag.Name) > 1 {
+		// Print negatable long option
+		return fmt.Sprintf("--%s%s", negation, optFlag.Name)
+	}
+
+	// Print only long option name, in fact - long options may be short if only short
+	// option was added by p.Add... function, for such case use dashes() function
+	// to print correct number of dashes before the option
+	return fmt.Sprintf("%s%s%s", dashes(optFlag.Name), optFlag.Name, valDescr())
+}
+
+// descrLongOpt returns the specification of the option and its description, the description
+// is placed to the column col in the two-column layout, see [OptsParser.SetTwoColumns]
+func (p *OptsParser) descrLongOpt(optFlag *flag.Flag, col int) string {
+	// Output buffer
+	out := bytes.NewBuffer([]byte{})
+	// Get long option description
+	descr := p.longOpts[optFlag.Name]
+
+	// Additional notes about the option
+	notes := []string{}
+
+	// Can option be repeated?
+	if _, ok := optFlag.Value.(multiValue); ok || descr.optType == typeCounter {
+		notes = append(notes, "can be repeated")
+	}
+
+	// Constraints of the option value, like bounds of the range
+	notes = append(notes, p.validatorsDescr(optFlag.Name)...)
+
+	// Is option bound to environment variable?
+	if env := p.envName(optFlag.Name); env != "" {
+		notes = append(notes, "env: " + env)
+	}
+
+	// Is option mutually exclusive with other options?
+	if excl := p.exclusiveWith(optFlag.Name); len(excl) != 0 {
+		notes = append(notes, "mutually exclusive with " + strings.Join(excl, ", "))
+	}
 
 	// Print default value if option is not required
 	if _, ok := p.required[optFlag.Name]; ok {
-		out.WriteString(" (required option)")
+		notes = append(notes, "required option")
 	} else {
 		defVal := optFlag.DefValue
 		if defVal == "" {
@@ -380,10 +675,16 @@ func (p *OptsParser) descrLongOpt(optFlag *flag.Flag) string {
 			defVal = `""`
 		}
 
-		fmt.Fprintf(out, " (default: %v)", defVal)
+		notes = append(notes, "default: " + defVal)
 	}
 
-	out.WriteString("\n")
+	// Print the specification, then usage information with notes
+	out.WriteString(p.layoutEntry(p.optSpec(optFlag), optFlag.Usage + " (" + strings.Join(notes, ", ") + ")", col))
+
+	// Print descriptions of choices if any
+	if co, ok := optFlag.Value.(choiceOption); ok {
+		out.WriteString(p.descrChoices(co, col))
+	}
 
 	// Return description
 	return out.String()
@@ -410,6 +711,19 @@ func (p *OptsParser) SetLongShortJoinStr(join string) *OptsParser {
 	return p
 }
 
+// SetNegationPrefix sets the prefix used to negate options added by [OptsParser.AddNegatableBool],
+// by default "no-" is used. For example, after calling SetNegationPrefix("disable-"), the option
+// "--cache" can be negated by "--disable-cache".
+func (p *OptsParser) SetNegationPrefix(prefix string) *OptsParser {
+	if prefix == "" {
+		doPanic("Negation prefix cannot be empty")
+	}
+
+	p.negPrefix = prefix
+
+	return p
+}
+
 // SetShortFirst sets to show the short form of options first in the Usage output.
 // By default, the long form is printed first.
 func (p *OptsParser) SetShortFirst(v bool) *OptsParser {
@@ -418,6 +732,64 @@ func (p *OptsParser) SetShortFirst(v bool) *OptsParser {
 	return p
 }
 
+// SetStrictDashes enables the strict GNU-like handling of dashes. By default, both forms of any option
+// can be used with either one or two dashes, e.g. "--config-path", "-config-path", "-c" and "--c" are
+// accepted. In the strict mode, long options are accepted only with two dashes and short options
+// only with one, otherwise the parsing fails with an error that wraps [ErrDashes]. Also, in the strict
+// mode an argument started with a single dash is always treated as a cluster of short options.
+func (p *OptsParser) SetStrictDashes(v bool) *OptsParser {
+	p.strictDashes = v
+
+	return p
+}
+
+// SetInterspersed allows options and positional arguments to be mixed in any order, e.g.
+// "my-app input.txt --verbose" is handled in the same way as "my-app --verbose input.txt". By default,
+// like the standard flag package, the parser stops processing options at the first non-option argument.
+// The "--" argument terminates options processing in both modes. Like GNU getopt, the interspersed mode
+// is disabled if the POSIXLY_CORRECT environment variable is set.
+func (p *OptsParser) SetInterspersed(v bool) *OptsParser {
+	p.interspersed = v
+
+	return p
+}
+
+// SetReportAll enables the mode in which the parser does not stop at the first unknown option
+// or invalid value, but collects all such problems, including missing required options, and
+// returns them as [ParseErrors]. The Usage output prints all collected errors as a list. Errors
+// that make further parsing impossible, like an unreadable configuration file, are still returned
+// immediately.
+func (p *OptsParser) SetReportAll(v bool) *OptsParser {
+	p.reportAll = v
+
+	return p
+}
+
+// SetOutput sets the destination for the Usage output and other messages of the parser. By default,
+// the help requested by the user and the output of the option added by [OptsParser.AddExplainConfig]
+// are printed to os.Stdout, the Usage output caused by errors is printed to os.Stderr. If the output
+// is set, all messages are printed to it.
+func (p *OptsParser) SetOutput(output io.Writer) *OptsParser {
+	p.FlagSet.SetOutput(output)
+	p.outputSet = output != nil
+
+	return p
+}
+
+// SetExitFunc sets the function used to terminate the program, by default os.Exit is used.
+// The function is called by [OptsParser.Usage] with [ExitCodeHelp] if the help was requested,
+// or [ExitCodeError] if the parsing failed. If the function returns, e.g. the parser is used
+// in a long-running process, the parsing functions return the error to the caller, the help
+// request is reported by [flag.ErrHelp]. Passing nil restores os.Exit.
+func (p *OptsParser) SetExitFunc(exit func(code int)) *OptsParser {
+	if exit == nil {
+		exit = os.Exit
+	}
+	p.exitFunc = exit
+
+	return p
+}
+
 // SetUsageOnFail sets the behavior of the [OptsParser.Parse] function. By default, the Parse call
 // causes the program to exit using [OptsParser.Usage]. If you call SetUsageOnFail(false),
 // the Parse function will return a parsing error to the caller instead of calling Usage.
@@ -428,24 +800,86 @@ func (p *OptsParser) SetUsageOnFail(v bool) *OptsParser {
 }
 
 // Usage outputs error message err[0] (if passed), a general description of the program
-// (if specified via [OptsParser.SetGeneralDescr]) and reference for each option. Then,
-// it exits the program using os.Exit(1).
+// (if specified via [OptsParser.SetGeneralDescr]) and reference for each option. Then, it exits
+// the program with [ExitCodeError] if the error was passed, or with [ExitCodeHelp] otherwise,
+// see [OptsParser.SetExitFunc]. Without the error, the output is treated as the requested help.
+// See [OptsParser.SetOutput] for the destination of the output.
 func (p *OptsParser) Usage(err ...error) {
+	// Help is printed to stdout, errors to stderr, unless the output is set
+	out, code := p.helpOutput(), ExitCodeHelp
+
 	// Check for custom error description
-	if len(err) != 0 {
-		fmt.Fprintf(p.Output(), "\nUsage ERROR: %v\n", err[0])
+	var errs ParseErrors
+	switch {
+	case len(err) == 0:
+		// No errors
+	case errors.As(err[0], &errs):
+		out, code = p.Output(), ExitCodeError
+		fmt.Fprintf(out, "\nUsage ERROR:\n")
+		for _, e := range errs {
+			fmt.Fprintf(out, "  - %v\n", e)
+		}
+	default:
+		out, code = p.Output(), ExitCodeError
+		fmt.Fprintf(out, "\nUsage ERROR: %v\n", err[0])
 	}
 
+	p.WriteUsage(out)
+
+	// Exit the program
+	p.exit(code)
+
+	//
+	// XXX This point should be reached only in tests
+	//
+
+	// Mark that Usage has been called
+	p.usageTriggered = true
+}
+
+// WriteUsage writes the Usage output without any error message to w, unlike [OptsParser.Usage]
+// it never exits the program.
+func (p *OptsParser) WriteUsage(w io.Writer) {
 	if name := p.FlagSet.Name(); name == "" {
-		fmt.Fprintf(p.Output(), "\nUsage:\n")
+		fmt.Fprintf(w, "\nUsage:\n")
 	} else {
-		fmt.Fprintf(p.Output(), "\nUsage of %s:\n", name)
+		fmt.Fprintf(w, "\nUsage of %s:\n", name)
 	}
 
+	// Print synopsis if it is enabled or arguments are declared
+	p.descrSynopsis(w)
+
 	// Print common description if set
 	if p.generalDescr != "" {
-		fmt.Fprintf(p.Output(), "%s\n", p.generalDescr)
+		fmt.Fprintf(w, "%s\n", p.generalDescr)
+	}
+
+	// Print options, arguments and constraints, then commands and options of parent commands
+	p.descrOptions(w)
+	p.descrArgs(w)
+	p.descrConstraints(w)
+	p.descrCommands(w)
+}
+
+// helpOutput returns the output for the help requested by the user
+func (p *OptsParser) helpOutput() io.Writer {
+	if p.outputSet {
+		return p.Output()
+	}
+
+	return os.Stdout
+}
+
+// descrOptions writes references for each option and separators
+func (p *OptsParser) descrOptions(out io.Writer) {
+	// Column of descriptions in the two-column layout
+	specs := []string{}
+	for _, opt := range p.orderedList {
+		if _, ok := p.longOpts[opt]; ok {
+			specs = append(specs, p.optSpec(p.Lookup(opt)))
+		}
 	}
+	col := p.column(specs)
 
 	// Reset separators index
 	p.sepIndex = 0
@@ -458,27 +892,23 @@ func (p *OptsParser) Usage(err ...error) {
 			// Update the value of the next expected separator
 			nextSep = p.nextSep()
 			// Print separator, then continue to the next option
-			fmt.Fprintf(p.Output(), optIndent + "%s\n", f.Usage)
+			if f.Usage == "" {
+				fmt.Fprintf(out, "%s\n", p.optIndent)
+			} else {
+				fmt.Fprint(out, wrapText(f.Usage, p.optIndent, p.optIndent, p.width()))
+			}
 
 			continue
 		}
 
 		// Print option help info
-		fmt.Fprint(p.Output(), p.descrLongOpt(f))
-	}
-
-	// XXX This condition will not satisfied only in tests
-	if !p.usageNotExits {
-		// Then - do exit
-		os.Exit(1)
+		fmt.Fprint(out, p.descrLongOpt(f, col))
 	}
+}
 
-	//
-	// XXX This point should be reached only in tests
-	//
-
-	// Mark that Usage has been called
-	p.usageTriggered = true
+// exit terminates the program with the code, unless the exit function was replaced
+func (p *OptsParser) exit(code int) {
+	p.exitFunc(code)
 }
 
 func (p *OptsParser) nextSep() string {
@@ -5,8 +5,8 @@ import (
 	"errors"
 	"flag"
 	"fmt"
-	"io"
 	"os"
+	"reflect"
 	"sort"
 	"testing"
 	"time"
@@ -18,10 +18,15 @@ const (
 
 // newParser wraps creation of parser to prevent call of os.Exit() inside of Usage() function
 func newParser(name string, required ...string) *OptsParser {
-	p := NewParser(name, required...)
-
 	// Disallow Usage() do os.Exit()
-	p.usageNotExits = true
+	return NewParser(name, required...).SetExitFunc(func(int) {})
+}
+
+// newTestParser creates the parser for tests of parsing, it returns errors without calling Usage,
+// reads environment variables from env and binds options to them with the "APP" prefix
+func newTestParser(env map[string]string, required ...string) *OptsParser {
+	p := newParser(stubApp, required...).SetUsageOnFail(false).SetOutput(&bytes.Buffer{}).SetEnvPrefix("APP")
+	p.lookupEnv = testEnv(env)
 
 	return p
 }
@@ -29,16 +34,6 @@ func newParser(name string, required ...string) *OptsParser {
 func TestParser(t *testing.T) {
 	t.Parallel()
 
-	// Save current value of os.Args because it will be replaced by test values
-	origArgs := make([]string, len(os.Args))
-	copy(origArgs, os.Args)
-	// Get the binary name
-	binName := os.Args[0]
-	// Recover on exiting from function
-	defer func() {
-		os.Args = origArgs
-	}()
-
 	// Get tests names and sort them
 	names := make([]string, 0, len(parserTests))
 	for name := range parserTests {
@@ -48,98 +43,94 @@ func TestParser(t *testing.T) {
 
 	// Run tests sorted by names
 	for _, testN := range names {
-		// XXX Do not run the tests in parallel inside the loop, because
-		// each test has to update the shared value of os.Args
-
-		// Get test
-		test := parserTests[testN]
-
-		// Make a buffer to catch parser's output
-		tOut := &bytes.Buffer{}
-
-		//nolint:varnamelen	// Too obvious case in the test
-		// Create new parser
-		p := newParser(stubApp,	// application name
-			test.required...,
-		).SetOutput(tOut)
-
-		// Function to automate adding separators
-		sepN := -1
-		sep := func() {
-			if v, ok := test.separators[sepN]; ok {
-				p.AddSeparator(v)
-			}
-			sepN++
-		}
-		to := testOpts{} //nolint:varnamelen	// Too obvious case in the test
+		// Capture variables to parallel usage
+		testN, test := testN, parserTests[testN]
 
-		// Function to automate selection of option names
-		opt := func(ot, def string) string {
-			if test.keys == nil {
-				return def
+		t.Run(testN, func(t *testing.T) {
+			t.Parallel()
+
+			// Make a buffer to catch parser's output
+			tOut := &bytes.Buffer{}
+
+			//nolint:varnamelen	// Too obvious case in the test
+			// Create new parser
+			p := newParser(stubApp,	// application name
+				test.required...,
+			).SetOutput(tOut)
+
+			// Function to automate adding separators
+			sepN := -1
+			sep := func() {
+				if v, ok := test.separators[sepN]; ok {
+					p.AddSeparator(v)
+				}
+				sepN++
 			}
-			if v, ok := test.keys[ot]; ok {
-				return v
+			to := testOpts{} //nolint:varnamelen	// Too obvious case in the test
+
+			// Function to automate selection of option names
+			opt := func(ot, def string) string {
+				if test.keys == nil {
+					return def
+				}
+				if v, ok := test.keys[ot]; ok {
+					return v
+				}
+				return def
 			}
-			return def
-		}
 
-		sep()	//	[-1] some kind of additional description
-		sep()	//	[0]
-		p.AddBool(opt("bool", "bool-opt|b"), "boolean value", &to.vBool, test.defaults.vBool)
-		sep()	//	[1]
-		p.AddString(opt("string", "string-opt|s"), "string value", &to.vString, test.defaults.vString)
-		sep()	//	[2]
-		p.AddInt(opt("int", "int-opt|i"), "int value", &to.vInt,  test.defaults.vInt)
-		sep()	//	[3]
-		p.AddInt64(opt("int64", "int64-opt|I"), "int64 value", &to.vInt64, test.defaults.vInt64)
-		sep()	//	[4]
-		p.AddFloat64(opt("float64", "float64-opt|f"), "float64 value", &to.vFloat64, test.defaults.vFloat64)
-		sep()	//	[5]
-		p.AddDuration(opt("duration", "duration-opt|d"), "duration value", &to.vDuration, test.defaults.vDuration)
-		sep()	//	[6]
-		p.AddUint(opt("uint", "uint-opt|u"), "uint value", &to.vUint, test.defaults.vUint)
-		sep()	//	[7]
-		p.AddUint64(opt("uint64", "uint64-opt|U"), "uint64 value", &to.vUint64, test.defaults.vUint64)
-		sep()	//	[8]
-		p.AddVar(opt("var", "var-ymd-opt|V"), "var value", &to.vVar)
-		sep()	//	[9]
-
-
-		// Replace real command arguments
-		os.Args = append([]string{binName}, test.args...)
-
-		// Do parsing
-		p.Parse()	//nolint: errcheck
-
-		// Is test should be OK?
-		if test.needOK {
-			// Is it true?
-			if p.usageTriggered {
-				// False, Usage called
-				t.Errorf("%q parse failed: args - %#v, test output:" +
-					"\n-------- Start output --------\n%s\n-------- End output --------",
-					testN, test.args, tOut.String())
-				continue
+			sep()	//	[-1] some kind of additional description
+			sep()	//	[0]
+			p.AddBool(opt("bool", "bool-opt|b"), "boolean value", &to.vBool, test.defaults.vBool)
+			sep()	//	[1]
+			p.AddString(opt("string", "string-opt|s"), "string value", &to.vString, test.defaults.vString)
+			sep()	//	[2]
+			p.AddInt(opt("int", "int-opt|i"), "int value", &to.vInt,  test.defaults.vInt)
+			sep()	//	[3]
+			p.AddInt64(opt("int64", "int64-opt|I"), "int64 value", &to.vInt64, test.defaults.vInt64)
+			sep()	//	[4]
+			p.AddFloat64(opt("float64", "float64-opt|f"), "float64 value", &to.vFloat64, test.defaults.vFloat64)
+			sep()	//	[5]
+			p.AddDuration(opt("duration", "duration-opt|d"), "duration value", &to.vDuration, test.defaults.vDuration)
+			sep()	//	[6]
+			p.AddUint(opt("uint", "uint-opt|u"), "uint value", &to.vUint, test.defaults.vUint)
+			sep()	//	[7]
+			p.AddUint64(opt("uint64", "uint64-opt|U"), "uint64 value", &to.vUint64, test.defaults.vUint64)
+			sep()	//	[8]
+			p.AddVar(opt("var", "var-ymd-opt|V"), "var value", &to.vVar)
+			sep()	//	[9]
+
+			// Do parsing
+			p.ParseArgs(test.args)	//nolint: errcheck
+
+			// Is test should be OK?
+			if test.needOK {
+				// Is it true?
+				if p.usageTriggered {
+					// False, Usage called
+					t.Errorf("%q parse failed: args - %#v, test output:" +
+						"\n-------- Start output --------\n%s\n-------- End output --------",
+						testN, test.args, tOut.String())
+					return
+				}
+
+				// Need to compare parsed and expected results
+				if to != test.want {
+					t.Errorf("%q incorrect Parse result: want - %#v got - %#v, args - %#v", testN, test.want, to, test.args)
+				}
+
+				// Success
+				return
 			}
 
-			// Need to compare parsed and expected results
-			if to != test.want {
-				t.Errorf("%q incorrect Parse result: want - %#v got - %#v, args - %#v", testN, test.want, to, test.args)
-				continue
+			// Test should be failed
+			if !p.usageTriggered {
+				t.Errorf("%q incorrect Parse result - test should fail but it succeeds; got - %#v, args - %#v",
+					testN, to, test.args)
 			}
 
-			// Success, run next test
-			continue
-		}
-
-		// Test should be failed
-		if !p.usageTriggered {
-			t.Errorf("%q incorrect Parse result - test should fail but it succeeds; got - %#v, args - %#v",
-				testN, to, test.args)
-		}
-
-		// Success, test failed as expected
+			// Success, test failed as expected
+		})
 	}
 }
 
@@ -268,34 +259,23 @@ func TestUsage(t *testing.T) {
 func TestHelpOption(t *testing.T) {
 	t.Parallel()
 
-	// Save current value of os.Args because it will be replaced by test values
-	origArgs := make([]string, len(os.Args))
-	copy(origArgs, os.Args)
-	// Recover on exiting from function
-	defer func() {
-		os.Args = origArgs
-	}()
-
 	// Run tests
 	for _, args := range [][]string {
-		[]string{os.Args[0], "--help"},
-		[]string{os.Args[0], "--h"},
+		[]string{"--help"},
+		[]string{"--h"},
 	} {
 		// Get new parser
 		p, tOut := parserWithPredefinedUsage()
 
-		// Replace command line arguments
-		os.Args = args
-
 		// Parse options
-		if err := p.Parse(); !errors.Is(err, flag.ErrHelp) {
+		if err := p.ParseArgs(args); !errors.Is(err, flag.ErrHelp) {
 			t.Errorf("Parse returned unexpected error value - %v, want - %v", err, flag.ErrHelp)
 			t.FailNow()
 		}
 
 		// Check that Usage was not triggered
 		if !p.usageTriggered {
-			t.Errorf("Usage function was not called by arguments: %v", os.Args)
+			t.Errorf("Usage function was not called by arguments: %v", args)
 			t.FailNow()
 		}
 
@@ -311,6 +291,113 @@ func TestHelpOption(t *testing.T) {
 	}
 }
 
+func TestExitPolicy(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct{
+		args		[]string
+		usageOnFail	bool
+		code		int
+		errOut		bool
+	}{
+		`help`:					{args: []string{`--help`}, usageOnFail: true, code: ExitCodeHelp},
+		`help-no-usage-on-fail`:	{args: []string{`-h`}, code: ExitCodeHelp},
+		`error`:				{args: []string{`--unknown`}, usageOnFail: true, code: ExitCodeError, errOut: true},
+		`missing-required`:		{args: []string{}, usageOnFail: true, code: ExitCodeError, errOut: true},
+	}
+
+	for testN, test := range tests {
+		codes := []int{}
+		p := NewParser(stubApp, "name").SetUsageOnFail(test.usageOnFail).SetExitFunc(func(code int) {
+			codes = append(codes, code)
+		})
+		p.AddString("name", "name of instance", new(string), "")
+
+		// Help is printed to stdout, errors to stderr by default
+		if test.errOut {
+			p.FlagSet.SetOutput(&bytes.Buffer{})
+		} else {
+			p.SetOutput(&bytes.Buffer{})
+		}
+
+		if err := p.ParseArgs(test.args); err == nil {
+			t.Errorf("%q parse must fail but it succeeds", testN)
+			continue
+		}
+
+		// Usage is called once
+		if !reflect.DeepEqual(codes, []int{test.code}) {
+			t.Errorf("%q unexpected exit codes: want - %v got - %v", testN, []int{test.code}, codes)
+		}
+	}
+
+	// Default outputs
+	p := NewParser(stubApp)
+	if p.helpOutput() != os.Stdout || p.Output() != os.Stderr {
+		t.Errorf("help must be printed to stdout, errors to stderr")
+	}
+	p.SetOutput(&bytes.Buffer{})
+	if p.helpOutput() != p.Output() {
+		t.Errorf("help must be printed to the output set by SetOutput")
+	}
+	if p.SetExitFunc(nil); p.exitFunc == nil {
+		t.Errorf("exit function must be restored")
+	}
+}
+
+func TestWriteUsage(t *testing.T) {
+	t.Parallel()
+
+	p, tOut := parserWithPredefinedUsage()
+	p.SetExitFunc(func(code int) {
+		t.Errorf("WriteUsage must not exit, but exit(%d) was called", code)
+	})
+
+	out := &bytes.Buffer{}
+	p.WriteUsage(out)
+
+	if tOut.Len() != 0 {
+		t.Errorf("WriteUsage must not write to the parser output, got:\n%s", tOut.String())
+	}
+	if out.String() != expUsageOutput {
+		t.Errorf("output produced by WriteUsage is different from expexted, see below:\n" +
+			"\n-------- Want --------\n%s\n" +
+			"-------- Got --------\n%s\n",
+			expUsageOutput, out.String(),
+		)
+	}
+}
+
+func TestParseOSArgs(t *testing.T) {
+	// XXX Do not run this test in parallel because it replaces the shared value of os.Args
+
+	// Save current value of os.Args because it will be replaced by test values
+	origArgs := os.Args
+	// Recover on exiting from function
+	defer func() {
+		os.Args = origArgs
+	}()
+
+	// Replace real command arguments
+	os.Args = []string{origArgs[0], "--string-opt", "value", "command line arg#1"}
+
+	var strVal string
+	p := newParser(stubApp).SetOutput(&bytes.Buffer{})
+	p.AddString("string-opt|s", "string value", &strVal, "")
+
+	if err := p.Parse(); err != nil {
+		t.Errorf("Parse returned unexpected error: %v", err)
+		t.FailNow()
+	}
+
+	if strVal != "value" {
+		t.Errorf("Parse returned incorrect value - %q, want - %q", strVal, "value")
+	}
+	if args := p.Args(); len(args) != 1 || args[0] != "command line arg#1" {
+		t.Errorf("Parse returned incorrect arguments - %#v", args)
+	}
+}
+
 func parserWithPredefinedUsage() (*OptsParser, *bytes.Buffer) {
 	// Buffer to save Usage output
 	tOut := &bytes.Buffer{}
@@ -406,11 +493,90 @@ func TestUsageNoName(t *testing.T) {
 	}
 }
 
+func TestNegatableBool(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct{
+		prefix	string
+		args	[]string
+		dflt	bool
+		want	bool
+	}{
+		{ args: []string{`--no-cache`},	dflt: true,		want: false },
+		{ args: []string{`-no-cache`},	dflt: true,		want: false },
+		{ args: []string{`--cache`},	dflt: false,	want: true },
+		{ args: []string{`-c`},			dflt: false,	want: true },
+		{ args: []string{`--no-cache`, `--cache`},	dflt: false,	want: true },
+		{ args: []string{`--disable-cache`},	prefix: `disable-`,	dflt: true,	want: false },
+	}
+
+	for i, test := range tests {
+		p := newParser(stubApp).SetUsageOnFail(false).SetOutput(&bytes.Buffer{})
+		if test.prefix != "" {
+			p.SetNegationPrefix(test.prefix)
+		}
+
+		var val bool
+		p.AddNegatableBool("cache|c", "use cache", &val, test.dflt)
+
+		if err := p.ParseArgs(test.args); err != nil {
+			t.Errorf("[%d] parse of %#v failed: %v", i, test.args, err)
+			continue
+		}
+		if val != test.want {
+			t.Errorf("[%d] incorrect Parse result of %#v: want - %t got - %t", i, test.args, test.want, val)
+		}
+	}
+
+	// Failed cases
+	for args, want := range map[string]string{
+		`--no-cache=true`:	`negated flag does not accept a value: --no-cache=true`,
+		`--no-c`:			`flag provided but not defined: --no-c`,
+		`--no-debug`:		`flag provided but not defined: --no-debug`,
+	} {
+		p := newParser(stubApp).SetUsageOnFail(false).SetOutput(&bytes.Buffer{})
+		p.AddNegatableBool("cache|c", "use cache", new(bool), true)
+		p.AddBool("debug", "debug mode", new(bool), false)
+
+		if err := p.ParseArgs([]string{args}); err == nil || err.Error() != want {
+			t.Errorf("parse of %q returned unexpected error: want - %q got - %v", args, want, err)
+		}
+	}
+}
+
+func TestNegatableBoolUsage(t *testing.T) {
+	t.Parallel()
+
+	tOut := &bytes.Buffer{}
+	p := newParser(stubApp).SetOutput(tOut)
+	p.AddNegatableBool("cache|c", "use cache", new(bool), true)
+	p.AddNegatableBool("color", "colorize output", new(bool), false)
+	p.Usage()
+
+	want := `
+Usage of ` + stubApp + `:
+    --[no-]cache, -c
+      use cache (default: true)
+    --[no-]color
+      colorize output (default: false)
+`
+	if tOut.String() != want {
+		t.Errorf("output produced by Usage is different from expexted, see below:\n" +
+			"\n-------- Want --------\n%s\n" +
+			"-------- Got --------\n%s\n",
+			want, tOut.String(),
+		)
+	}
+}
+
 //
 // Functions required for testing
 //
-func (p *OptsParser) SetOutput(output io.Writer) *OptsParser {
-	p.FlagSet.SetOutput(output)
 
-	return p
+// testEnv returns a function to replace os.LookupEnv in tests
+func testEnv(env map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		val, ok := env[name]
+		return val, ok
+	}
 }
@@ -0,0 +1,219 @@
+package optsparser
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const respFilePrefixDefault = '@'
+
+// argOrigin describes where the argument came from
+type argOrigin struct {
+	idx		int		// index of the argument in the original arguments list
+	file	string	// response file, if the argument was read from it
+	line	int		// line in the response file
+}
+
+// respExpander expands response files in the arguments list
+type respExpander struct {
+	prefix		string
+	args		[]string
+	origins		[]argOrigin
+	stack		[]string	// stack of included files to detect cycles
+	terminated	bool		// options terminator was found, do not expand anything after it
+}
+
+// SetResponseFiles enables or disables expansion of response files. If enabled (by default),
+// each argument in the form "@path" is replaced by the arguments read from the file "path".
+// Arguments in the file are separated by whitespace and can be quoted by single or double quotes,
+// the backslash escapes the next character, the "#" character at the beginning of an argument
+// starts a comment till the end of line. Response files can include other response files, relative
+// paths of included files are resolved from the directory of the including file. Arguments after
+// the "--" terminator are not expanded.
+func (p *OptsParser) SetResponseFiles(v bool) *OptsParser {
+	p.noRespFiles = !v
+
+	return p
+}
+
+// SetResponseFilePrefix sets the prefix character of response files, by default '@' is used.
+func (p *OptsParser) SetResponseFilePrefix(prefix rune) *OptsParser {
+	p.respFilePrefix = prefix
+
+	return p
+}
+
+// expandArgs replaces response files in the arguments list by their content
+func (p *OptsParser) expandArgs(args []string) ([]string, []argOrigin, error) {
+	e := &respExpander{
+		prefix:		string(p.respFilePrefix),
+		args:		make([]string, 0, len(args)),
+		origins:	make([]argOrigin, 0, len(args)),
+	}
+
+	for i, arg := range args {
+		if err := e.expand(arg, argOrigin{idx: i}, "", !p.noRespFiles); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return e.args, e.origins, nil
+}
+
+// expand adds the argument to the list, or the content of the response file if the argument refers to it
+func (e *respExpander) expand(arg string, origin argOrigin, dir string, enabled bool) error {
+	if arg == "--" {
+		e.terminated = true
+	}
+
+	// Is it a response file?
+	if !enabled || e.terminated || !strings.HasPrefix(arg, e.prefix) || len(arg) == len(e.prefix) {
+		// No, add the argument as is
+		e.args = append(e.args, arg)
+		e.origins = append(e.origins, origin)
+
+		return nil
+	}
+
+	path := arg[len(e.prefix):]
+	if dir != "" && !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+
+	// Check for cycles
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return e.errorf(origin, "invalid response file path %q: %w", path, err)
+	}
+	for _, included := range e.stack {
+		if included == abs {
+			return e.errorf(origin, "recursive inclusion of response file %q", path)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return e.errorf(origin, "cannot read response file: %w", err)
+	}
+
+	tokens, err := splitRespFile(string(data))
+	if err != nil {
+		return fmt.Errorf("response file %s:%w", path, err)
+	}
+
+	e.stack = append(e.stack, abs)
+	defer func() { e.stack = e.stack[:len(e.stack)-1] }()
+
+	for _, tok := range tokens {
+		tokOrigin := argOrigin{idx: origin.idx, file: path, line: tok.line}
+		if err := e.expand(tok.text, tokOrigin, filepath.Dir(path), true); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// errorf returns the error with the location of the argument, if it was read from a response file
+func (e *respExpander) errorf(origin argOrigin, format string, args ...any) error {
+	return origin.locate(fmt.Errorf(format, args...))
+}
+
+// locate adds the location of the argument to the error, if the argument was read from a response file
+func (o argOrigin) locate(err error) error {
+	if o.file == "" {
+		return err
+	}
+
+	return fmt.Errorf("response file %s:%d: %w", o.file, o.line, err)
+}
+
+// respToken is an argument read from a response file
+type respToken struct {
+	text	string
+	line	int
+}
+
+// splitRespFile splits the content of a response file to arguments using shell-like rules
+func splitRespFile(data string) ([]respToken, error) {
+	tokens := []respToken{}
+
+	var tok strings.Builder
+	inTok := false
+	line, tokLine := 1, 1
+	quote, quoteLine := rune(0), 0
+	escaped := false
+
+	for _, c := range data {
+		switch {
+		case quote == '#':
+			// Skip the comment
+		case escaped:
+			escaped = false
+			// Backslash-newline is the line continuation
+			if c != '\n' {
+				tok.WriteRune(c)
+			}
+		case quote == '\'':
+			if c == '\'' {
+				quote = 0
+			} else {
+				tok.WriteRune(c)
+			}
+		case quote == '"':
+			switch c {
+			case '"':
+				quote = 0
+			case '\\':
+				escaped = true
+			default:
+				tok.WriteRune(c)
+			}
+		case c == '\\':
+			escaped = true
+			if !inTok {
+				inTok, tokLine = true, line
+			}
+		case c == '\'' || c == '"':
+			quote, quoteLine = c, line
+			if !inTok {
+				inTok, tokLine = true, line
+			}
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			if inTok {
+				tokens = append(tokens, respToken{text: tok.String(), line: tokLine})
+				tok.Reset()
+				inTok = false
+			}
+		case c == '#' && !inTok:
+			// Comment till the end of line
+			quote = '#'
+		default:
+			tok.WriteRune(c)
+			if !inTok {
+				inTok, tokLine = true, line
+			}
+		}
+
+		if c == '\n' {
+			line++
+			// Comment is finished
+			if quote == '#' {
+				quote = 0
+			}
+		}
+	}
+
+	switch {
+	case quote == '\'' || quote == '"':
+		return nil, fmt.Errorf("%d: unterminated quote", quoteLine)
+	case escaped:
+		return nil, fmt.Errorf("%d: backslash at the end of file", line)
+	case inTok:
+		tokens = append(tokens, respToken{text: tok.String(), line: tokLine})
+	}
+
+	return tokens, nil
+}
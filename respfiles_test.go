@@ -0,0 +1,146 @@
+package optsparser
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSplitRespFile(t *testing.T) {
+	t.Parallel()
+
+	data := "# comment line\n" +
+		"--name 'single quoted # not a comment'\n" +
+		"  \"double \\\"quoted\\\"\" plain\\ escaped # comment\n" +
+		"multi\\\nline '' last"
+
+	want := []respToken{
+		{text: `--name`, line: 2},
+		{text: `single quoted # not a comment`, line: 2},
+		{text: `double "quoted"`, line: 3},
+		{text: `plain escaped`, line: 3},
+		{text: `multiline`, line: 4},
+		{text: ``, line: 5},
+		{text: `last`, line: 5},
+	}
+
+	got, err := splitRespFile(data)
+	if err != nil {
+		t.Errorf("split failed: %v", err)
+		t.FailNow()
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("incorrect split result: want - %#v got - %#v", want, got)
+	}
+
+	// Invalid cases
+	for data, want := range map[string]string{
+		"a\n'b\nc":		`2: unterminated quote`,
+		"a \"b":		`1: unterminated quote`,
+		"a\nb\\":		`2: backslash at the end of file`,
+	} {
+		if _, err := splitRespFile(data); err == nil || err.Error() != want {
+			t.Errorf("split of %q returned unexpected error: want - %q got - %v", data, want, err)
+		}
+	}
+}
+
+func writeRespFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("cannot write response file: %v", err)
+	}
+
+	return path
+}
+
+func TestResponseFiles(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	inner := writeRespFile(t, dir, "inner.rsp", "--workers 4\n")
+	// Inner file is included by the relative path
+	outer := writeRespFile(t, dir, "outer.rsp", "# options\n-n 'from file'\n@inner.rsp\n")
+
+	var name string
+	var workers int
+	newP := func() *OptsParser {
+		p := newParser(stubApp).SetUsageOnFail(false).SetOutput(&bytes.Buffer{})
+		p.AddString("name|n", "name of instance", &name, "default")
+		p.AddInt("workers|w", "number of workers", &workers, 1)
+		return p
+	}
+
+	p := newP()
+	if err := p.ParseArgs([]string{`-w`, `2`, `@` + outer, `arg`, `--`, `@` + outer}); err != nil {
+		t.Errorf("parse failed: %v", err)
+		t.FailNow()
+	}
+
+	if name != `from file` || workers != 4 {
+		t.Errorf("incorrect Parse result: name - %q workers - %d", name, workers)
+	}
+	if want := []string{`arg`, `--`, `@` + outer}; !reflect.DeepEqual(p.Args(), want) {
+		t.Errorf("incorrect arguments: want - %#v got - %#v", want, p.Args())
+	}
+
+	// Check sources
+	for opt, want := range map[string]Source{
+		`n`:	{Kind: SourceArgs, ArgIndex: 2, File: outer, Line: 2},
+		`w`:	{Kind: SourceArgs, ArgIndex: 2, File: inner, Line: 1},
+	} {
+		if got := p.Source(opt); got != want {
+			t.Errorf("incorrect source of %q: want - %#v got - %#v", opt, want, got)
+		}
+	}
+
+	// Disabled response files
+	p = newP().SetResponseFiles(false)
+	if err := p.ParseArgs([]string{`-n`, `@` + outer}); err != nil || name != `@` + outer {
+		t.Errorf("parse with disabled response files failed: %v, name - %q", err, name)
+	}
+
+	// Changed prefix, the inner file is not expanded because it is included with the old prefix
+	p = newP().SetResponseFilePrefix('+')
+	if err := p.ParseArgs([]string{`+` + outer}); err != nil || name != `from file` || workers != 1 {
+		t.Errorf("parse with changed prefix failed: %v, name - %q, workers - %d", err, name, workers)
+	}
+	if want := []string{`@inner.rsp`}; !reflect.DeepEqual(p.Args(), want) {
+		t.Errorf("incorrect arguments: want - %#v got - %#v", want, p.Args())
+	}
+}
+
+func TestResponseFilesFail(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	cycle := writeRespFile(t, dir, "cycle.rsp", "-w 1\n\n@cycle2.rsp\n")
+	cycle2 := writeRespFile(t, dir, "cycle2.rsp", "@" + cycle)
+	broken := writeRespFile(t, dir, "broken.rsp", "-n\n'unterminated\n")
+	missing := filepath.Join(dir, "missing.rsp")
+	includesMissing := writeRespFile(t, dir, "includes.rsp", "\n@missing.rsp\n")
+	unknown := writeRespFile(t, dir, "unknown.rsp", "-n x\n--bogus\n")
+	invalid := writeRespFile(t, dir, "invalid.rsp", "\n-n x -w x\n")
+
+	for args, want := range map[string]string{
+		`@` + cycle:			`response file ` + cycle2 + `:1: recursive inclusion of response file "` + cycle + `"`,
+		`@` + broken:			`response file ` + broken + `:2: unterminated quote`,
+		`@` + includesMissing:	`response file ` + includesMissing + `:2: cannot read response file: open ` +
+			missing + `: no such file or directory`,
+		`@` + missing:			`cannot read response file: open ` + missing + `: no such file or directory`,
+		`@` + unknown:			`response file ` + unknown + `:2: flag provided but not defined: --bogus`,
+		`@` + invalid:			`response file ` + invalid + `:2: invalid value "x" for flag -w: parse error`,
+	} {
+		p := newParser(stubApp).SetUsageOnFail(false).SetOutput(&bytes.Buffer{})
+		p.AddString("name|n", "name of instance", new(string), "")
+		p.AddInt("workers|w", "number of workers", new(int), 1)
+
+		if err := p.ParseArgs([]string{args}); err == nil || err.Error() != want {
+			t.Errorf("parse of %q returned unexpected error: want - %q got - %v", args, want, err)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package optsparser
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+const secretMask = "******"
+
+// SourceKind is the kind of source of an option value
+type SourceKind int
+
+const (
+	// SourceDefault means that the option has the default value
+	SourceDefault SourceKind = iota
+	// SourceEnv means that the value was set by an environment variable
+	SourceEnv
+	// SourceFile means that the value was set by the configuration file
+	SourceFile
+	// SourceArgs means that the value was set by the command line
+	SourceArgs
+	// SourceImplied means that the value was implied by another option, see [OptsParser.SetImplies]
+	SourceImplied
+)
+
+// Source describes where the effective value of an option came from
+type Source struct {
+	Kind		SourceKind
+	Env			string	// name of the environment variable, for SourceEnv
+	File		string	// path to the configuration file, for SourceFile, or to the response file, for SourceArgs
+	Line		int		// line in the File
+	ArgIndex	int		// index of the argument in the list passed to ParseArgs, for SourceArgs
+	Option		string	// long name of the option which implied the value, for SourceImplied
+}
+
+// String returns a human-readable description of the source
+func (s Source) String() string {
+	switch s.Kind {
+	case SourceEnv:
+		return "env " + s.Env
+	case SourceFile:
+		return fmt.Sprintf("file %s:%d", s.File, s.Line)
+	case SourceArgs:
+		// Arguments are numbered from 1, as in os.Args
+		if s.File != "" {
+			return fmt.Sprintf("response file %s:%d (argument #%d)", s.File, s.Line, s.ArgIndex + 1)
+		}
+		return fmt.Sprintf("argument #%d", s.ArgIndex + 1)
+	case SourceImplied:
+		return "implied by " + dashes(s.Option) + s.Option
+	case SourceDefault:
+	}
+
+	return "default"
+}
+
+// Source returns the source of the effective value of the option optName. If the option was
+// set several times, the last source is returned. The optName can be either long or short name
+// of the option. Source panics if the option was not added.
+func (p *OptsParser) Source(optName string) Source {
+	long, _ := p.optDescr(optName)
+
+	return p.sources[long]
+}
+
+// SetSecret marks the option optName as secret, the value of such option is masked in the output
+// of the option added by [OptsParser.AddExplainConfig]. The optName can be either long or short name
+// of the option. SetSecret panics if the option was not added.
+func (p *OptsParser) SetSecret(optName string) *OptsParser {
+	_, descr := p.optDescr(optName)
+	descr.secret = true
+
+	return p
+}
+
+// AddExplainConfig adds a boolean option with specified option name and usage string. If the option
+// is set, after loading values from all sources the parser prints a table of effective values of all
+// options and their sources (see [OptsParser.Source]) and exits the program with zero exit code.
+// Values of options marked by [OptsParser.SetSecret] are masked.
+func (p *OptsParser) AddExplainConfig(optName, usage string) {
+	long, _, _ := strings.Cut(optName, "|")
+
+	p.AddBool(optName, usage, &p.explain, false)
+	p.explainOpt = long
+}
+
+// setOpt sets the value of the option and records its source, the option keeps
+// its previous value and source if the new value is rejected by validators
+func (p *OptsParser) setOpt(name, value string, src Source) error {
+	long := p.longName(name)
+	f := p.Lookup(name)
+	if f == nil {
+		return p.FlagSet.Set(name, value)	//nolint:wrapcheck // error is wrapped by callers
+	}
+
+	// Values without the typed value, e.g. options added by AddFunc, cannot be restored,
+	// so their raw values are checked before setting
+	g, typed := f.Value.(flag.Getter)
+	if !typed {
+		if err := p.validate(long, value); err != nil {
+			return err
+		}
+	}
+
+	restore := func() {}
+	if typed && len(p.longOpts[long].validators) != 0 {
+		restore = saveValue(f.Value)
+	}
+
+	if err := p.FlagSet.Set(name, value); err != nil {
+		return err	//nolint:wrapcheck // error is wrapped by callers
+	}
+
+	if typed {
+		if err := p.validate(long, g.Get()); err != nil {
+			restore()
+
+			// The FlagSet treats the option as set, which is wrong if it was not set by a valid value before
+			if _, ok := p.sources[long]; !ok {
+				p.rejected[long] = true
+			}
+
+			return err
+		}
+	}
+
+	delete(p.rejected, long)
+	p.sources[long] = src
+
+	return nil
+}
+
+// explainConfig prints effective values of all options and their sources
+func (p *OptsParser) explainConfig() {
+	tw := newTableWriter(p.helpOutput())
+
+	fmt.Fprintln(tw, "OPTION\tVALUE\tSOURCE")
+	// Options of parent commands are also in effect
+	for _, q := range p.chain() {
+		q.explainOpts(tw)
+	}
+
+	tw.Flush()
+}
+
+// explainOpts writes rows of the explanation table for options of the parser
+func (p *OptsParser) explainOpts(tw io.Writer) {
+	for _, long := range p.orderedList {
+		descr, ok := p.longOpts[long]
+		if !ok || descr.optType == typeSeparator || long == p.explainOpt {
+			continue
+		}
+
+		value := p.Lookup(long).Value.String()
+		if descr.secret && value != "" {
+			value = secretMask
+		}
+		if value == "" {
+			value = `""`
+		}
+
+		fmt.Fprintf(tw, "%s%s\t%s\t%s\n", dashes(long), long, value, p.sources[long])
+	}
+}
+
+func newTableWriter(out io.Writer) *tabwriter.Writer {
+	return tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+}
@@ -0,0 +1,101 @@
+package optsparser
+
+import (
+	"bytes"
+	"testing"
+)
+
+// sourcesOpts adds options which values can come from all sources used by tests
+func sourcesOpts(p *OptsParser) *OptsParser {
+	p.AddConfig("config|c", "path to configuration", "")
+	p.AddString("name|n", "name of instance", new(string), "default")
+	p.AddInt("workers|w", "number of workers", new(int), 1)
+	p.AddString("password", "password to access", new(string), "")
+	p.AddString("token", "access token", new(string), "")
+	p.AddExplainConfig("explain-config", "print effective configuration")
+
+	return p.SetSecret("password").SetSecret("token")
+}
+
+func TestSources(t *testing.T) {
+	t.Parallel()
+
+	cfg := writeConfig(t, "app.conf", "# comment\nworkers = 4\nname = file\n")
+
+	p := sourcesOpts(newTestParser(map[string]string{`APP_PASSWORD`: `secret`}))
+	if err := p.ParseArgs([]string{`-c`, cfg, `extra`, `-n`, `args`}); err != nil {
+		t.Errorf("parse failed: %v", err)
+		t.FailNow()
+	}
+
+	for opt, want := range map[string]Source{
+		`config`:	{Kind: SourceArgs, ArgIndex: 0},
+		`n`:		{Kind: SourceFile, File: cfg, Line: 3},
+		`workers`:	{Kind: SourceFile, File: cfg, Line: 2},
+		`password`:	{Kind: SourceEnv, Env: `APP_PASSWORD`},
+		`token`:	{Kind: SourceDefault},
+	} {
+		if got := p.Source(opt); got != want {
+			t.Errorf("incorrect source of %q: want - %#v got - %#v", opt, want, got)
+		}
+	}
+
+	// Check interspersed arguments
+	p = sourcesOpts(newTestParser(nil)).SetInterspersed(true)
+	if err := p.ParseArgs([]string{`extra`, `-w`, `10`, `-n`, `args`}); err != nil {
+		t.Errorf("parse failed: %v", err)
+		t.FailNow()
+	}
+	if got, want := p.Source("n"), (Source{Kind: SourceArgs, ArgIndex: 3}); got != want {
+		t.Errorf("incorrect source of %q: want - %#v got - %#v", "n", want, got)
+	}
+}
+
+func TestSourceString(t *testing.T) {
+	t.Parallel()
+
+	for src, want := range map[Source]string{
+		{Kind: SourceDefault}:								`default`,
+		{Kind: SourceEnv, Env: `APP_NAME`}:					`env APP_NAME`,
+		{Kind: SourceFile, File: `/etc/app.conf`, Line: 3}:	`file /etc/app.conf:3`,
+		{Kind: SourceArgs, ArgIndex: 2}:					`argument #3`,
+		{Kind: SourceArgs, ArgIndex: 0, File: `a.rsp`, Line: 5}:	`response file a.rsp:5 (argument #1)`,
+	} {
+		if src.String() != want {
+			t.Errorf("incorrect string representation of %#v: want - %q got - %q", src, want, src.String())
+		}
+	}
+}
+
+func TestExplainConfig(t *testing.T) {
+	t.Parallel()
+
+	cfg := writeConfig(t, "app.conf", "workers = 4\ntoken = abc\n")
+
+	tOut := &bytes.Buffer{}
+	p := sourcesOpts(newTestParser(map[string]string{`APP_PASSWORD`: `secret`})).SetOutput(tOut)
+	if err := p.ParseArgs([]string{`--explain-config`, `-c`, cfg, `-n`, `args`}); err != nil {
+		t.Errorf("parse failed: %v", err)
+		t.FailNow()
+	}
+
+	// Expected table, columns are aligned by the same writer as used by the parser
+	wantOut := &bytes.Buffer{}
+	tw := newTableWriter(wantOut)
+	tw.Write([]byte("OPTION\tVALUE\tSOURCE\n" +	//nolint:errcheck
+		"--config\t" + cfg + "\targument #2\n" +
+		"--name\targs\targument #4\n" +
+		"--workers\t4\tfile " + cfg + ":1\n" +
+		"--password\t******\tenv APP_PASSWORD\n" +
+		"--token\t******\tfile " + cfg + ":2\n"))
+	tw.Flush()
+	want := wantOut.String()
+
+	if tOut.String() != want {
+		t.Errorf("explain output is different from expexted, see below:\n" +
+			"\n-------- Want --------\n%s\n" +
+			"-------- Got --------\n%s\n",
+			want, tOut.String(),
+		)
+	}
+}
@@ -0,0 +1,170 @@
+package optsparser
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// SetAutoSynopsis enables the synopsis line generated from registered options and arguments, which
+// is printed by the Usage function before the general description. For example:
+//  my-app --config-path string [--workers int] [--json | --yaml] [--tag string]... SRC [FILES...]
+//
+// Required options are shown bare, optional ones in brackets, repeatable options are followed by "...".
+// Mutually exclusive options (see [OptsParser.SetExclusive]) are shown as one group, in parentheses if any
+// of them is required, otherwise in brackets. Positional arguments declared by [AddArg], [AddOptArg] and
+// [AddArgs] are shown at the end. The synopsis is wrapped to the width of the output, see [OptsParser.SetWidth].
+// Without the automatic synopsis, the short synopsis with "[options]" is printed if positional arguments
+// are declared.
+func (p *OptsParser) SetAutoSynopsis(v bool) *OptsParser {
+	p.autoSynopsis = v
+
+	return p
+}
+
+// descrSynopsis writes the synopsis line
+func (p *OptsParser) descrSynopsis(out io.Writer) {
+	items := []string{}
+	switch {
+	case p.autoSynopsis:
+		items = p.synopsisItems()
+	case len(p.args) != 0:
+		if len(p.orderedList) != 0 || p.parent != nil {
+			items = append(items, "[options]")
+		}
+	default:
+		// Nothing to print
+		return
+	}
+
+	if len(p.args) != 0 {
+		items = append(items, p.argsSynopsis())
+	}
+	if len(p.commands) != 0 {
+		items = append(items, "COMMAND ...")
+	}
+
+	fmt.Fprint(out, wrapItems(p.optIndent, p.Name(), items, p.width()))
+}
+
+// synopsisItems returns items of the synopsis for options in order of the addition
+func (p *OptsParser) synopsisItems() []string {
+	items := []string{}
+	done := map[string]bool{}
+
+	for _, long := range p.orderedList {
+		if _, ok := p.longOpts[long]; !ok || done[long] {
+			// Separator or the option from already printed group
+			continue
+		}
+
+		// Print mutually exclusive options as one group
+		if group := p.synopsisGroup(long, done); len(group) > 1 {
+			names := make([]string, 0, len(group))
+			required := false
+			for _, opt := range group {
+				done[opt] = true
+				names = append(names, p.synopsisOpt(opt))
+				_, isReq := p.required[opt]
+				required = required || isReq
+			}
+
+			if required {
+				items = append(items, "(" + strings.Join(names, " | ") + ")")
+			} else {
+				items = append(items, "[" + strings.Join(names, " | ") + "]")
+			}
+
+			continue
+		}
+
+		done[long] = true
+		item := p.synopsisOpt(long)
+		repeat := strings.HasSuffix(item, "...")
+		if repeat {
+			item = strings.TrimSuffix(item, "...")
+		}
+		if _, ok := p.required[long]; !ok {
+			item = "[" + item + "]"
+		}
+		if repeat {
+			item += "..."
+		}
+
+		items = append(items, item)
+	}
+
+	return items
+}
+
+// synopsisGroup returns options of the first group of mutually exclusive options
+// with the option, except options that are already printed
+func (p *OptsParser) synopsisGroup(long string, done map[string]bool) []string {
+	for _, group := range p.exclusive {
+		if !contains(group, long) {
+			continue
+		}
+
+		rest := []string{}
+		for _, opt := range group {
+			if !done[opt] {
+				rest = append(rest, opt)
+			}
+		}
+		if len(rest) > 1 {
+			return rest
+		}
+	}
+
+	return nil
+}
+
+// synopsisOpt returns the option with the placeholder of the value, repeatable options are followed by "..."
+func (p *OptsParser) synopsisOpt(long string) string {
+	descr := p.longOpts[long]
+
+	item := dashes(long) + long
+	switch {
+	case descr.negatable:
+		item = "--[" + p.negPrefix + "]" + long
+	case descr.optType == typeBool, descr.optType == typeCounter:
+		// Value is not required
+	default:
+		item += " " + descr.optType
+	}
+
+	if _, ok := p.Lookup(long).Value.(multiValue); ok || descr.optType == typeCounter {
+		item += "..."
+	}
+
+	return item
+}
+
+// wrapItems joins the name and items to lines of the width, items are never split, continuation
+// lines are aligned to the first item
+func wrapItems(indent, name string, items []string, width int) string {
+	line := indent + name
+	if name != "" {
+		indent += strings.Repeat(" ", utf8.RuneCountInString(name) + 1)
+	}
+
+	out := &strings.Builder{}
+	empty := name == ""
+	for _, item := range items {
+		switch {
+		case empty:
+			line += item
+			empty = false
+		case utf8.RuneCountInString(line) + 1 + utf8.RuneCountInString(item) > width &&
+				strings.TrimSpace(line) != name:
+			out.WriteString(line + "\n")
+			line = indent + item
+		default:
+			line += " " + item
+		}
+	}
+	out.WriteString(line + "\n")
+
+	return out.String()
+}
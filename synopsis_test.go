@@ -0,0 +1,131 @@
+package optsparser
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// synopsisOpts adds options and positional arguments used by tests
+func synopsisOpts(p *OptsParser) *OptsParser {
+	p.AddString("config-path|c", "path to configuration", new(string), "")
+	p.AddInt("workers|w", "number of workers", new(int), 1)
+	p.AddSeparator("Output:")
+	p.AddBool("json", "JSON output", new(bool), false)
+	p.AddBool("yaml", "YAML output", new(bool), false)
+	p.AddChoice("format", "output format", new(string), "", "text", "html")
+	p.AddNegatableBool("cache", "use cache", new(bool), true)
+	p.AddStrings("tag|t", "tags", new([]string), nil)
+	p.AddCounter("v", "verbosity", new(int), 0)
+	p.AddString("password", "password", new(string), "")
+	p.AddString("password-file", "file with password", new(string), "")
+	AddArg(p, "FILE", "input file", new(string))
+	AddArgs(p, "FILES", "other files", new([]string), 0, 0)
+
+	return p.SetAutoSynopsis(true).SetExclusive("json", "yaml").SetExclusive("password", "password-file", "format")
+}
+
+func TestSynopsis(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct{
+		env		map[string]string
+		want	string
+	}{
+		`default-width`:	{
+			want:	`    app --config-path string [--workers int] [--json | --yaml]
+        (--password string | --password-file string | --format text|html)
+        [--[no-]cache] [--tag string]... [-v]... FILE [FILES...]
+`,
+		},
+		`invalid-width`:	{
+			env:	map[string]string{`COLUMNS`: `x`},
+			want:	`    app --config-path string [--workers int] [--json | --yaml]
+        (--password string | --password-file string | --format text|html)
+        [--[no-]cache] [--tag string]... [-v]... FILE [FILES...]
+`,
+		},
+		`narrow`:			{
+			env:	map[string]string{`COLUMNS`: `50`},
+			want:	`    app --config-path string [--workers int]
+        [--json | --yaml]
+        (--password string | --password-file string | --format text|html)
+        [--[no-]cache] [--tag string]... [-v]...
+        FILE [FILES...]
+`,
+		},
+		`wide`:				{
+			env:	map[string]string{`COLUMNS`: `200`},
+			want:	`    app --config-path string [--workers int] [--json | --yaml]` +
+					` (--password string | --password-file string | --format text|html)` +
+					` [--[no-]cache] [--tag string]... [-v]... FILE [FILES...]
+`,
+		},
+	}
+
+	for testN, test := range tests {
+		tOut := &bytes.Buffer{}
+		p := synopsisOpts(newParser("app", "config-path", "format"))
+		p.lookupEnv = testEnv(test.env)
+
+		p.descrSynopsis(tOut)
+		if tOut.String() != test.want {
+			t.Errorf("%q incorrect synopsis: want -\n%s\ngot -\n%s", testN, test.want, tOut.String())
+		}
+	}
+}
+
+func TestSynopsisUsage(t *testing.T) {
+	t.Parallel()
+
+	tOut := &bytes.Buffer{}
+	p := newParser(stubApp).SetOutput(tOut).SetAutoSynopsis(true).SetGeneralDescr("Tool to manage things.")
+	p.AddBool("verbose|v", "verbose output", new(bool), false)
+	cmd := p.AddCommand("get", "get the thing")
+	cmd.AddString("format", "output format", new(string), "json")
+	AddArg(cmd, "NAME", "name of the thing", new(string))
+
+	p.WriteUsage(tOut)
+	want := `
+Usage of ` + stubApp + `:
+    ` + stubApp + ` [--verbose] COMMAND ...
+Tool to manage things.
+    --verbose[=true|false], -v[=true|false]
+      verbose output (default: false)
+
+Commands:
+    get
+      get the thing
+`
+	if tOut.String() != want {
+		t.Errorf("incorrect usage output: want -\n%s\ngot -\n%s", want, tOut.String())
+	}
+
+	// Command inherits the setting
+	tOut.Reset()
+	cmd.WriteUsage(tOut)
+	want = `
+Usage of ` + stubApp + ` get:
+    ` + stubApp + ` get [--format string] NAME
+    --format string
+      output format (default: json)
+
+Arguments:
+    NAME
+      name of the thing
+
+Global options:
+    --verbose[=true|false], -v[=true|false]
+      verbose output (default: false)
+`
+	if tOut.String() != want {
+		t.Errorf("incorrect usage output: want -\n%s\ngot -\n%s", want, tOut.String())
+	}
+
+	// Synopsis is not printed by default
+	tOut.Reset()
+	p.SetAutoSynopsis(false).WriteUsage(tOut)
+	if out := tOut.String(); !strings.HasPrefix(out, "\nUsage of " + stubApp + ":\nTool") {
+		t.Errorf("unexpected synopsis in the usage output:\n%s", out)
+	}
+}
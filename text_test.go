@@ -0,0 +1,101 @@
+package optsparser
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestTextVar(t *testing.T) {
+	t.Parallel()
+
+	var ip net.IP
+	var num big.Int
+	var words []string
+
+	p := newParser(stubApp).SetUsageOnFail(false).SetOutput(&bytes.Buffer{})
+	p.AddTextVar("listen|l", "address to listen", &ip, net.IPv4(127, 0, 0, 1), "IP")
+	p.AddTextVar("number", "big number", &num, nil, "")
+	p.AddFunc("words|w", "space separated words", func(s string) error {
+		if s == "" {
+			return errors.New("empty value")
+		}
+		words = append(words, strings.Fields(s)...)
+		return nil
+	}, "WORDS")
+
+	// Check default value
+	if !ip.Equal(net.IPv4(127, 0, 0, 1)) {
+		t.Errorf("incorrect default value: %v", ip)
+	}
+
+	args := []string{`-l`, `::1`, `--number`, `123456789012345678901234567890`, `-w`, `a b`, `--words=c`}
+	if err := p.ParseArgs(args); err != nil {
+		t.Errorf("parse of %#v failed: %v", args, err)
+		t.FailNow()
+	}
+
+	if !ip.Equal(net.IPv6loopback) {
+		t.Errorf("incorrect IP value: %v", ip)
+	}
+	if num.String() != `123456789012345678901234567890` {
+		t.Errorf("incorrect big number value: %v", num.String())
+	}
+	if strings.Join(words, ",") != `a,b,c` {
+		t.Errorf("incorrect words: %#v", words)
+	}
+
+	// Invalid values
+	for args, want := range map[string]string{
+		`--listen=1.2.3`:	`invalid value "1.2.3" for flag --listen: invalid IP address: 1.2.3`,
+		`--words=`:			`invalid value "" for flag --words: empty value`,
+	} {
+		if err := p.ParseArgs([]string{args}); err == nil || err.Error() != want {
+			t.Errorf("parse of %q returned unexpected error: want - %q got - %v", args, want, err)
+		}
+	}
+}
+
+func TestTextVarUsage(t *testing.T) {
+	t.Parallel()
+
+	tOut := &bytes.Buffer{}
+	p := newParser(stubApp).SetOutput(tOut)
+	p.AddTextVar("listen|l", "address to listen", new(net.IP), net.IPv4(127, 0, 0, 1), "IP")
+	p.AddTextVar("number", "big number", new(big.Int), big.NewInt(10), "")
+	p.AddFunc("w", "words", func(string) error { return nil }, "WORDS")
+	p.Usage()
+
+	want := `
+Usage of ` + stubApp + `:
+    --listen IP, -l IP
+      address to listen (default: 127.0.0.1)
+    --number value
+      big number (default: 10)
+    -w WORDS
+      words (default: "")
+`
+	if tOut.String() != want {
+		t.Errorf("output produced by Usage is different from expexted, see below:\n" +
+			"\n-------- Want --------\n%s\n" +
+			"-------- Got --------\n%s\n",
+			want, tOut.String(),
+		)
+	}
+}
+
+func TestTextVarPanic(t *testing.T) {
+	t.Parallel()
+
+	defer func() {
+		if _, ok := recover().(OptsPanic); !ok {
+			t.Errorf("invalid default value did not cause a panic, but it must")
+		}
+	}()
+
+	p := newParser(stubApp)
+	p.AddTextVar("listen", "address to listen", new(net.IP), net.IP{1, 2, 3}, "IP")
+}
@@ -0,0 +1,314 @@
+package optsparser
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+)
+
+// ErrDashes is returned by the parser in the strict dashes mode, when a long option is
+// specified with a single dash or a short option is specified with two dashes.
+// See [OptsParser.SetStrictDashes] for details.
+var ErrDashes = errors.New("wrong number of dashes")
+
+// optAssign describes the value assigned to an option by the arguments list
+type optAssign struct {
+	name	string	// option name as it registered in the FlagSet
+	value	string	// value to set
+	dash	string	// dashes used before the option name in the arguments list
+	idx		int		// index of the option in the arguments list
+	owner	*OptsParser	// parser which the option belongs to
+}
+
+// argsScanner splits the arguments list to the option assignments and positional arguments
+type argsScanner struct {
+	p			*OptsParser
+	args		[]string
+	origins		[]argOrigin	// origins of arguments, nil if locations are not required
+	pos			int
+	optIdx		int		// index of the currently processed option argument
+	assigns		[]optAssign
+	positional	[]string
+	permute		bool
+}
+
+// boolFlag is the interface implemented by options that do not require a value,
+// it is the same interface as used by the standard flag package
+type boolFlag interface {
+	IsBoolFlag() bool
+}
+
+func isBoolFlag(f *flag.Flag) bool {
+	bf, ok := f.Value.(boolFlag)
+	return ok && bf.IsBoolFlag()
+}
+
+// tokenize converts arguments list to the list of option assignments and the list of positional
+// arguments. Unlike the standard flag package, it supports getopt-like clusters of short options,
+// like "-vxf archive.tar", "-ofile" or "-j4". Errors of arguments read from response files
+// contain locations of the arguments, if origins are passed.
+func (p *OptsParser) tokenize(args []string, origins []argOrigin) ([]optAssign, []string, error) {
+	s := &argsScanner{
+		p:			p,
+		args:		args,
+		origins:	origins,
+		assigns:	make([]optAssign, 0, len(args)),
+		positional:	[]string{},
+		permute:	p.permute(),
+	}
+
+	for s.pos < len(s.args) {
+		arg := s.args[s.pos]
+
+		switch {
+		// Is it the options terminator?
+		case arg == "--":
+			// Skip terminator, all remaining arguments are positional
+			return s.assigns, append(s.positional, s.args[s.pos+1:]...), nil
+		// Is it a non-option argument that can be skipped to process the next options?
+		case (len(arg) < 2 || arg[0] != '-') && s.permute:
+			s.positional = append(s.positional, arg)
+			s.pos++
+
+			continue
+		// Is it a non-option argument?
+		case len(arg) < 2 || arg[0] != '-':
+			// Stop options processing, all remaining arguments are positional
+			return s.assigns, append(s.positional, s.args[s.pos:]...), nil
+		}
+
+		// Skip the option argument itself
+		s.optIdx = s.pos
+		s.pos++
+
+		var err error
+		if strings.HasPrefix(arg, "--") {
+			err = s.option("--", arg[2:])
+		} else {
+			err = s.single(arg[1:])
+		}
+		if err != nil && s.origins != nil {
+			err = s.origins[s.optIdx].locate(err)
+		}
+
+		// Help request stops parsing in any mode
+		if err != nil && (errors.Is(err, flag.ErrHelp) || p.report(err) != nil) {
+			return nil, nil, err
+		}
+	}
+
+	// All arguments are processed
+	return s.assigns, s.positional, nil
+}
+
+// permute returns true if options and positional arguments can be mixed
+func (p *OptsParser) permute() bool {
+	// The first positional argument of a parser with commands is the name of command
+	if !p.interspersed || len(p.commands) != 0 {
+		return false
+	}
+
+	// Permutation can be disabled by the environment like GNU getopt does
+	_, posix := p.lookupEnv(envPosixlyCorrect)
+
+	return !posix
+}
+
+// single processes the argument started with single dash
+func (s *argsScanner) single(arg string) error {
+	name, _, _ := strings.Cut(arg, "=")
+
+	if s.p.strictDashes {
+		switch {
+		// Is it the cluster of short options?
+		case s.p.allShorts(name):
+			return s.cluster(arg)
+		// Is it the long option with single dash?
+		case len(name) > 1 && (s.p.lookupOpt(name) != nil || s.p.negated(name) != ""):
+			return fmt.Errorf("%w: long option %q requires two dashes: --%s", ErrDashes, name, name)
+		// Is it the request of help?
+		case isHelp(name):
+			return flag.ErrHelp
+		}
+
+		// Treat as the cluster, it can contain a value of the last option
+		return s.cluster(arg)
+	}
+
+	// Is it the option known as is or the request of help?
+	if s.p.lookupOpt(name) != nil || s.p.negated(name) != "" || isHelp(name) {
+		// Process it as the standard flag package does
+		return s.option("-", arg)
+	}
+
+	// Treat as the cluster of short options
+	return s.cluster(arg)
+}
+
+// option processes the option specified in the form "name" or "name=value"
+func (s *argsScanner) option(dash, arg string) error {
+	if arg == "" || arg[0] == '-' || arg[0] == '=' {
+		return fmt.Errorf("bad flag syntax: %s%s", dash, arg)
+	}
+
+	name, value, hasValue := strings.Cut(arg, "=")
+
+	f := s.p.lookupOpt(name)
+	if f != nil && s.p.strictDashes && dash == "--" && len(name) == 1 {
+		return fmt.Errorf("%w: short option %q requires a single dash: -%s", ErrDashes, name, name)
+	}
+	if f == nil {
+		// Is it a negated option?
+		if long := s.p.negated(name); long != "" {
+			if hasValue {
+				return fmt.Errorf("negated flag does not accept a value: %s%s", dash, arg)
+			}
+			s.add(long, "false", dash)
+
+			return nil
+		}
+
+		if isHelp(name) {
+			return flag.ErrHelp
+		}
+		return &UnknownOptionError{Option: name, dash: dash}
+	}
+
+	switch {
+	case hasValue:
+		// Value is set explicitly
+	case isBoolFlag(f):
+		value = "true"
+	default:
+		// Value is the next argument
+		var err error
+		if value, err = s.next(dash, name); err != nil {
+			return err
+		}
+	}
+
+	s.add(name, value, dash)
+
+	return nil
+}
+
+// cluster processes the cluster of short options, like "-vxf", "-ofile", "-vj4"
+func (s *argsScanner) cluster(cl string) error {
+	for i := 0; i < len(cl); i++ {
+		name := cl[i:i+1]
+
+		f := s.p.lookupOpt(name)
+		if f == nil {
+			if i == 0 {
+				// The whole argument is unknown, report it as is
+				return &UnknownOptionError{Option: cl, dash: "-"}
+			}
+			return &UnknownOptionError{Option: name, Cluster: cl, dash: "-"}
+		}
+
+		// Option does not require a value?
+		if isBoolFlag(f) {
+			s.add(name, "true", "-")
+			continue
+		}
+
+		// Option requires a value, it is either the rest of the cluster or the next argument
+		rest := cl[i+1:]
+		if rest == "" {
+			value, err := s.next("-", name)
+			if err != nil {
+				return err
+			}
+			s.add(name, value, "-")
+
+			return nil
+		}
+
+		// Option is not the first in the cluster and the rest looks like other short options,
+		// most likely the value-taking option was placed in the middle of cluster by mistake
+		if i != 0 && s.p.allShorts(rest) {
+			return fmt.Errorf("option -%s requires a value and must be the last in cluster -%s", name, cl)
+		}
+
+		// The rest of the cluster is the value
+		s.add(name, rest, "-")
+
+		return nil
+	}
+
+	return nil
+}
+
+func (s *argsScanner) next(dash, name string) (string, error) {
+	if s.pos >= len(s.args) {
+		return "", fmt.Errorf("flag needs an argument: %s%s", dash, name)
+	}
+
+	defer func() { s.pos++ }()
+	return s.args[s.pos], nil
+}
+
+func (s *argsScanner) add(name, value, dash string) {
+	s.assigns = append(s.assigns, optAssign{
+		name:	name,
+		value:	value,
+		dash:	dash,
+		idx:	s.optIdx,
+		owner:	s.p.owner(name),
+	})
+}
+
+// negated returns the name of the negatable option if name is the negated form of
+// this option, otherwise it returns an empty string
+func (p *OptsParser) negated(name string) string {
+	long := strings.TrimPrefix(name, p.negPrefix)
+	if long == name {
+		return ""
+	}
+
+	// Only long forms of options can be negated, options of parent commands are also considered
+	for q := p; q != nil; q = q.parent {
+		if descr, ok := q.longOpts[long]; ok && descr.negatable && len(long) > 1 {
+			return long
+		}
+	}
+
+	return ""
+}
+
+// lookupOpt returns the option with the name, options of parent commands are also considered
+func (p *OptsParser) lookupOpt(name string) *flag.Flag {
+	if owner := p.owner(name); owner != nil {
+		return owner.Lookup(name)
+	}
+
+	return nil
+}
+
+// owner returns the parser which the option with the name belongs to,
+// it is either the parser itself or one of the parent commands
+func (p *OptsParser) owner(name string) *OptsParser {
+	for q := p; q != nil; q = q.parent {
+		if q.Lookup(name) != nil {
+			return q
+		}
+	}
+
+	return nil
+}
+
+// allShorts returns true if each character of str is a registered short option
+func (p *OptsParser) allShorts(str string) bool {
+	for i := 0; i < len(str); i++ {
+		if p.lookupOpt(str[i:i+1]) == nil {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isHelp(name string) bool {
+	return name == "help" || name == "h"
+}
@@ -0,0 +1,263 @@
+package optsparser
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"reflect"
+	"testing"
+)
+
+type testBundleOpts struct {
+	verbose	bool
+	extract	bool
+	file	string
+	output	string
+	jobs	int
+	long	bool
+}
+
+func newBundleParser(to *testBundleOpts) *OptsParser {
+	p := newParser(stubApp).SetUsageOnFail(false).SetOutput(&bytes.Buffer{})
+
+	p.AddBool("verbose|v", "verbose output", &to.verbose, false)
+	p.AddBool("extract|x", "extract files", &to.extract, false)
+	p.AddString("file|f", "archive file", &to.file, "")
+	p.AddString("output|o", "output file", &to.output, "")
+	p.AddInt("jobs|j", "number of jobs", &to.jobs, 1)
+	p.AddBool("vx", "long option that looks like a cluster", &to.long, false)
+
+	return p
+}
+
+func TestShortBundling(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct{
+		args	[]string
+		want	testBundleOpts
+		posArgs	[]string
+	}{
+		`cluster-with-value-next`:	{
+			args:		[]string{`-vxf`, `archive.tar`, `arg`},
+			want:		testBundleOpts{verbose: true, extract: true, file: `archive.tar`, jobs: 1},
+			posArgs:	[]string{`arg`},
+		},
+		`attached-value`:	{
+			args:		[]string{`-ofile`, `-j4`},
+			want:		testBundleOpts{output: `file`, jobs: 4},
+			posArgs:	[]string{},
+		},
+		`cluster-with-attached-value`:	{
+			args:		[]string{`-vj4`, `--`, `-x`},
+			want:		testBundleOpts{verbose: true, jobs: 4},
+			posArgs:	[]string{`-x`},
+		},
+		`attached-value-looks-like-shorts`:	{
+			args:		[]string{`-ovx`},
+			want:		testBundleOpts{output: `vx`, jobs: 1},
+			posArgs:	[]string{},
+		},
+		`known-long-option-wins`:	{
+			args:		[]string{`-vx`},
+			want:		testBundleOpts{long: true, jobs: 1},
+			posArgs:	[]string{},
+		},
+		`single-dash-long-option`:	{
+			args:		[]string{`-file=archive.tar`, `-jobs`, `-2`, `-`},
+			want:		testBundleOpts{file: `archive.tar`, jobs: -2},
+			posArgs:	[]string{`-`},
+		},
+	}
+
+	for testN, test := range tests {
+		testN, test := testN, test
+		t.Run(testN, func(t *testing.T) {
+			t.Parallel()
+
+			to := testBundleOpts{}
+			p := newBundleParser(&to)
+
+			if err := p.ParseArgs(test.args); err != nil {
+				t.Errorf("%q parse failed: %v", testN, err)
+				return
+			}
+
+			if to != test.want {
+				t.Errorf("%q incorrect Parse result: want - %#v got - %#v", testN, test.want, to)
+			}
+			if !reflect.DeepEqual(p.Args(), test.posArgs) {
+				t.Errorf("%q incorrect arguments: want - %#v got - %#v", testN, test.posArgs, p.Args())
+			}
+		})
+	}
+}
+
+func TestShortBundlingFail(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct{
+		args	[]string
+		want	string
+	}{
+		`value-taking-in-middle`:	{
+			args:	[]string{`-vfx`, `archive.tar`},
+			want:	`option -f requires a value and must be the last in cluster -vfx`,
+		},
+		`unknown-in-cluster`:	{
+			args:	[]string{`-vyx`},
+			want:	`flag provided but not defined: -y (in cluster -vyx)`,
+		},
+		`unknown-cluster`:	{
+			args:	[]string{`-yes`},
+			want:	`flag provided but not defined: -yes`,
+		},
+		`missing-value`:	{
+			args:	[]string{`-vf`},
+			want:	`flag needs an argument: -f`,
+		},
+		`missing-value-long`:	{
+			args:	[]string{`--file`},
+			want:	`flag needs an argument: --file`,
+		},
+		`bad-syntax`:	{
+			args:	[]string{`---file`},
+			want:	`bad flag syntax: ---file`,
+		},
+		`invalid-value`:	{
+			args:	[]string{`-j4x`},
+			want:	`invalid value "4x" for flag -j: parse error`,
+		},
+	}
+
+	for testN, test := range tests {
+		testN, test := testN, test
+		t.Run(testN, func(t *testing.T) {
+			t.Parallel()
+
+			p := newBundleParser(&testBundleOpts{})
+
+			err := p.ParseArgs(test.args)
+			if err == nil {
+				t.Errorf("%q parse must fail but it succeeds", testN)
+				return
+			}
+			if err.Error() != test.want {
+				t.Errorf("%q unexpected error: want - %q got - %q", testN, test.want, err.Error())
+			}
+		})
+	}
+}
+
+func TestShortBundlingHelp(t *testing.T) {
+	t.Parallel()
+
+	for _, arg := range []string{`-h`, `-help`, `--help`} {
+		p := newBundleParser(&testBundleOpts{})
+		if err := p.ParseArgs([]string{arg}); !errors.Is(err, flag.ErrHelp) {
+			t.Errorf("Parse(%q) returned unexpected error value - %v, want - %v", arg, err, flag.ErrHelp)
+		}
+	}
+}
+
+func TestStrictDashes(t *testing.T) {
+	t.Parallel()
+
+	// Successful cases
+	for _, args := range [][]string{
+		{`--file`, `archive.tar`, `-vx`},
+		{`-vxfarchive.tar`},
+		{`--file=archive.tar`, `-v`, `-x`},
+	} {
+		to := testBundleOpts{}
+		p := newBundleParser(&to).SetStrictDashes(true)
+
+		if err := p.ParseArgs(args); err != nil {
+			t.Errorf("parse of %#v failed: %v", args, err)
+			continue
+		}
+
+		if want := (testBundleOpts{verbose: true, extract: true, file: `archive.tar`, jobs: 1}); to != want {
+			t.Errorf("incorrect Parse result of %#v: want - %#v got - %#v", args, want, to)
+		}
+	}
+
+	// Failed cases
+	for args, want := range map[string]string{
+		`-file`:		`wrong number of dashes: long option "file" requires two dashes: --file`,
+		`-jobs=10`:		`wrong number of dashes: long option "jobs" requires two dashes: --jobs`,
+		`--f`:			`wrong number of dashes: short option "f" requires a single dash: -f`,
+		`--j=10`:		`wrong number of dashes: short option "j" requires a single dash: -j`,
+	} {
+		p := newBundleParser(&testBundleOpts{}).SetStrictDashes(true)
+
+		err := p.ParseArgs([]string{args, `value`})
+		if !errors.Is(err, ErrDashes) {
+			t.Errorf("parse of %q returned unexpected error: want - %v got - %v", args, ErrDashes, err)
+			continue
+		}
+		if err.Error() != want {
+			t.Errorf("parse of %q returned unexpected error: want - %q got - %q", args, want, err.Error())
+		}
+	}
+
+	// Help is still available
+	for _, arg := range []string{`-h`, `-help`, `--help`} {
+		p := newBundleParser(&testBundleOpts{}).SetStrictDashes(true)
+		if err := p.ParseArgs([]string{arg}); !errors.Is(err, flag.ErrHelp) {
+			t.Errorf("Parse(%q) returned unexpected error value - %v, want - %v", arg, err, flag.ErrHelp)
+		}
+	}
+}
+
+func TestInterspersed(t *testing.T) {
+	t.Parallel()
+
+	args := []string{`input.txt`, `-v`, `output.txt`, `--file`, `archive.tar`, `-`, `--`, `-x`}
+
+	tests := map[string]struct{
+		interspersed	bool
+		env				map[string]string
+		want			testBundleOpts
+		posArgs			[]string
+	}{
+		`disabled`:	{
+			want:		testBundleOpts{jobs: 1},
+			posArgs:	args,
+		},
+		`enabled`:	{
+			interspersed:	true,
+			want:			testBundleOpts{verbose: true, file: `archive.tar`, jobs: 1},
+			posArgs:		[]string{`input.txt`, `output.txt`, `-`, `-x`},
+		},
+		`disabled-by-env`:	{
+			interspersed:	true,
+			env:			map[string]string{envPosixlyCorrect: ""},
+			want:			testBundleOpts{jobs: 1},
+			posArgs:		args,
+		},
+	}
+
+	for testN, test := range tests {
+		testN, test := testN, test
+		t.Run(testN, func(t *testing.T) {
+			t.Parallel()
+
+			to := testBundleOpts{}
+			p := newBundleParser(&to).SetInterspersed(test.interspersed)
+			p.lookupEnv = testEnv(test.env)
+
+			if err := p.ParseArgs(args); err != nil {
+				t.Errorf("%q parse failed: %v", testN, err)
+				return
+			}
+
+			if to != test.want {
+				t.Errorf("%q incorrect Parse result: want - %#v got - %#v", testN, test.want, to)
+			}
+			if !reflect.DeepEqual(p.Args(), test.posArgs) {
+				t.Errorf("%q incorrect arguments: want - %#v got - %#v", testN, test.posArgs, p.Args())
+			}
+		})
+	}
+}
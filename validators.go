@@ -0,0 +1,159 @@
+package optsparser
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// Number is the set of numeric types of values supported by range validators
+type Number interface {
+	int | int64 | uint | uint64 | float64 | time.Duration
+}
+
+// Validator checks the value of an option, see [OptsParser.SetValidators]. Validators are created
+// by [Validate] or by built-in functions like [Range], [Min], [Max], [Positive], [Match] and [NonEmpty].
+type Validator struct {
+	descr	string				// description printed in the Usage output
+	check	func(any) error
+	accepts	func(any) bool		// reports whether the validator can check the value of such type
+}
+
+// Validate returns a validator that calls fn with the value of the option. The type T must be the type
+// of the option value, e.g. int for options added by [OptsParser.AddInt] or []string for options added
+// by [OptsParser.AddStrings], or string for options added by [OptsParser.AddFunc]. The non-empty descr
+// is printed in the Usage output of the option.
+func Validate[T any](descr string, fn func(T) error) Validator {
+	return Validator{
+		descr:		descr,
+		check:		func(v any) error { return fn(v.(T)) },	//nolint:forcetypeassert // type is checked by accepts
+		accepts:	func(v any) bool { _, ok := v.(T); return ok },
+	}
+}
+
+// Range returns a validator that requires the value to be in the range from min to max inclusive
+func Range[T Number](min, max T) Validator {
+	return Validate(fmt.Sprintf("range: %v..%v", min, max), func(v T) error {
+		if v < min || v > max {
+			return fmt.Errorf("must be in the range %v..%v", min, max)
+		}
+
+		return nil
+	})
+}
+
+// Min returns a validator that requires the value to be greater than or equal to min
+func Min[T Number](min T) Validator {
+	return Validate(fmt.Sprintf("min: %v", min), func(v T) error {
+		if v < min {
+			return fmt.Errorf("must be at least %v", min)
+		}
+
+		return nil
+	})
+}
+
+// Max returns a validator that requires the value to be less than or equal to max
+func Max[T Number](max T) Validator {
+	return Validate(fmt.Sprintf("max: %v", max), func(v T) error {
+		if v > max {
+			return fmt.Errorf("must be at most %v", max)
+		}
+
+		return nil
+	})
+}
+
+// Positive returns a validator that requires the value to be greater than zero
+func Positive[T Number]() Validator {
+	return Validate("positive", func(v T) error {
+		if v <= 0 {
+			return errors.New("must be positive")
+		}
+
+		return nil
+	})
+}
+
+// Match returns a validator that requires the string value to match the regular expression re
+func Match(re *regexp.Regexp) Validator {
+	return Validate("format: " + re.String(), func(v string) error {
+		if !re.MatchString(v) {
+			return fmt.Errorf("must match %q", re.String())
+		}
+
+		return nil
+	})
+}
+
+// NonEmpty returns a validator that requires the string value to be non-empty
+func NonEmpty() Validator {
+	return Validate("non-empty", func(v string) error {
+		if v == "" {
+			return errors.New("must not be empty")
+		}
+
+		return nil
+	})
+}
+
+// SetValidators sets validators of the option optName, e.g. SetValidators("workers", Range(1, 256)).
+// Validators are called in the specified order each time the option is set by any source, the first
+// failed validator fails the parsing with [*InvalidValueError] which wraps the error of the validator.
+// Default values are not validated. Non-empty descriptions of validators, like bounds of ranges, are
+// printed in the Usage output of the option. The optName can be either long or short name of the option.
+// SetValidators panics if the option was not added or a validator cannot check values of the option type.
+func (p *OptsParser) SetValidators(optName string, validators ...Validator) *OptsParser {
+	long, descr := p.optDescr(optName)
+
+	val := optValue(p.Lookup(long))
+	for _, v := range validators {
+		if !v.accepts(val) {
+			doPanic("Validator %q cannot check values of type %T of option %q", v.descr, val, optName)
+		}
+	}
+
+	descr.validators = append(descr.validators, validators...)
+
+	return p
+}
+
+// validate checks the value of the long option by its validators, the value is either
+// typed or raw, if the option does not provide its typed value
+func (p *OptsParser) validate(long string, val any) error {
+	descr, ok := p.longOpts[long]
+	if !ok {
+		return nil
+	}
+
+	for _, v := range descr.validators {
+		if err := v.check(val); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validatorsDescr returns descriptions of validators of the option
+func (p *OptsParser) validatorsDescr(long string) []string {
+	descrs := []string{}
+	for _, v := range p.longOpts[long].validators {
+		if v.descr != "" {
+			descrs = append(descrs, v.descr)
+		}
+	}
+
+	return descrs
+}
+
+// optValue returns the typed value of the option, if it is available
+func optValue(f *flag.Flag) any {
+	if g, ok := f.Value.(flag.Getter); ok {
+		return g.Get()
+	}
+
+	return f.Value.String()
+}
@@ -0,0 +1,235 @@
+package optsparser
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+)
+
+type testValidOpts struct {
+	workers	int
+	timeout	time.Duration
+	name	string
+	ratio	float64
+	tags	[]string
+}
+
+// validOpts adds options and their validators used by tests
+func validOpts(p *OptsParser, to *testValidOpts) *OptsParser {
+	p.AddInt("workers|w", "number of workers", &to.workers, 4)
+	p.AddDuration("timeout", "timeout of operations", &to.timeout, 0)
+	p.AddString("name", "name of instance", &to.name, "")
+	p.AddFloat64("ratio", "compression ratio", &to.ratio, 0.5)
+	p.AddStrings("tag", "tags of instance", &to.tags, nil)
+
+	return p.
+		SetValidators("w", Range(1, 256)).
+		SetValidators("timeout", Positive[time.Duration](), Max(time.Hour)).
+		SetValidators("name", NonEmpty(), Match(regexp.MustCompile(`^[a-z][a-z0-9-]*$`))).
+		SetValidators("ratio", Min(0.0), Max(1.0)).
+		SetValidators("tag", Validate("at most 2 tags", func(tags []string) error {
+			if len(tags) > 2 {
+				return fmt.Errorf("too many tags: %d", len(tags))
+			}
+			return nil
+		}))
+}
+
+func TestValidators(t *testing.T) {
+	t.Parallel()
+
+	to := testValidOpts{}
+	p := validOpts(newTestParser(map[string]string{`APP_TIMEOUT`: `1m`}), &to)
+	if err := p.ParseArgs([]string{`-w`, `256`, `--name`, `db-1`, `--ratio=1`, `--tag`, `a`, `--tag`, `b`}); err != nil {
+		t.Errorf("parse failed: %v", err)
+		t.FailNow()
+	}
+
+	want := testValidOpts{workers: 256, timeout: time.Minute, name: `db-1`, ratio: 1, tags: []string{`a`, `b`}}
+	if fmt.Sprint(to) != fmt.Sprint(want) {
+		t.Errorf("incorrect Parse result: want - %#v got - %#v", want, to)
+	}
+
+	// Default values are not validated
+	if err := validOpts(newTestParser(nil), &testValidOpts{}).ParseArgs([]string{}); err != nil {
+		t.Errorf("parse of default values failed: %v", err)
+	}
+
+	// Options added by AddFunc are validated by raw values
+	hooks := []string{}
+	p = newTestParser(nil)
+	p.AddFunc("hook", "hook to run", func(s string) error { hooks = append(hooks, s); return nil }, "cmd")
+	p.SetValidators("hook", NonEmpty())
+	if err := p.ParseArgs([]string{`--hook`, `backup`}); err != nil || len(hooks) != 1 || hooks[0] != `backup` {
+		t.Errorf("unexpected result: %v, %#v", err, hooks)
+	}
+	if err := p.ParseArgs([]string{`--hook=`}); err == nil ||
+			err.Error() != `invalid value "" for flag --hook: must not be empty` {
+		t.Errorf("unexpected error of empty hook: %v", err)
+	}
+	if len(hooks) != 1 {
+		t.Errorf("function must not be called with rejected value: %#v", hooks)
+	}
+}
+
+func TestValidatorsFail(t *testing.T) {
+	t.Parallel()
+
+	cfg := writeConfig(t, "app.conf", "ratio = 1.5\n")
+
+	tests := map[string]struct{
+		args	[]string
+		env		map[string]string
+		want	InvalidValueError
+		text	string
+	}{
+		`range`:		{
+			args:	[]string{`-w0`},
+			want:	InvalidValueError{Option: `w`, Value: `0`, Source: Source{Kind: SourceArgs}},
+			text:	`invalid value "0" for flag -w: must be in the range 1..256`,
+		},
+		`positive`:		{
+			args:	[]string{`--timeout=0s`},
+			want:	InvalidValueError{Option: `timeout`, Value: `0s`, Source: Source{Kind: SourceArgs}},
+			text:	`invalid value "0s" for flag --timeout: must be positive`,
+		},
+		`max-env`:		{
+			env:	map[string]string{`APP_TIMEOUT`: `2h`},
+			want:	InvalidValueError{Option: `timeout`, Value: `2h`, Source: Source{Kind: SourceEnv, Env: `APP_TIMEOUT`}},
+			text:	`invalid value "2h" for flag --timeout from environment variable APP_TIMEOUT: must be at most 1h0m0s`,
+		},
+		`non-empty`:	{
+			args:	[]string{`--name=`},
+			want:	InvalidValueError{Option: `name`, Value: ``, Source: Source{Kind: SourceArgs}},
+			text:	`invalid value "" for flag --name: must not be empty`,
+		},
+		`match`:		{
+			args:	[]string{`--name`, `DB`},
+			want:	InvalidValueError{Option: `name`, Value: `DB`, Source: Source{Kind: SourceArgs}},
+			text:	`invalid value "DB" for flag --name: must match "^[a-z][a-z0-9-]*$"`,
+		},
+		`max-config`:	{
+			args:	[]string{`--config`, cfg},
+			want:	InvalidValueError{Option: `ratio`, Value: `1.5`, Source: Source{Kind: SourceFile, File: cfg, Line: 1}},
+			text:	cfg + `:1: invalid value "1.5" for option "ratio": must be at most 1`,
+		},
+		`custom`:		{
+			args:	[]string{`--tag=a`, `--tag`, `b`, `--tag`, `c`},
+			want:	InvalidValueError{Option: `tag`, Value: `c`, Source: Source{Kind: SourceArgs, ArgIndex: 3}},
+			text:	`invalid value "c" for flag --tag: too many tags: 3`,
+		},
+	}
+
+	for testN, test := range tests {
+		p := validOpts(newTestParser(test.env), &testValidOpts{})
+		p.AddConfig("config", "path to configuration", "")
+		err := p.ParseArgs(test.args)
+
+		var ivErr *InvalidValueError
+		if !errors.As(err, &ivErr) {
+			t.Errorf("%q unexpected error type: want - %T got - %T (%v)", testN, ivErr, err, err)
+			continue
+		}
+		if ivErr.Option != test.want.Option || ivErr.Value != test.want.Value || ivErr.Source != test.want.Source {
+			t.Errorf("%q incorrect error: want - %#v got - %#v", testN, test.want, *ivErr)
+		}
+		if err.Error() != test.text {
+			t.Errorf("%q unexpected error: want - %q got - %q", testN, test.text, err.Error())
+		}
+	}
+
+	// Rejected values do not replace previous values
+	to := testValidOpts{}
+	p := validOpts(newTestParser(nil), &to).SetReportAll(true)
+	err := p.ParseArgs([]string{`-w`, `8`, `-w`, `999`, `--tag`, `a`, `--tag`, `b`, `--tag`, `c`, `--ratio=2`})
+	if err == nil {
+		t.Errorf("invalid values must fail the parsing")
+	}
+	want := testValidOpts{workers: 8, ratio: 0.5, tags: []string{`a`, `b`}}
+	if fmt.Sprint(to) != fmt.Sprint(want) {
+		t.Errorf("incorrect values after failed validation: want - %#v got - %#v", want, to)
+	}
+	if src := p.Source(`workers`); src != (Source{Kind: SourceArgs, ArgIndex: 0}) {
+		t.Errorf("incorrect source after failed validation: %#v", src)
+	}
+
+	// Rejected values do not satisfy required options and constraints
+	p = newTestParser(nil, "workers").SetReportAll(true)
+	p.AddInt("workers|w", "number of workers", new(int), 4)
+	p.AddBool("json", "JSON output", new(bool), false)
+	p.SetValidators("w", Range(1, 10)).SetRequires("workers", "json")
+	err = p.ParseArgs([]string{`-w`, `999`})
+
+	var errs ParseErrors
+	if !errors.As(err, &errs) {
+		t.Errorf("unexpected error type: want - %T got - %T (%v)", errs, err, err)
+		t.FailNow()
+	}
+	wantErrs := []string{
+		`invalid value "999" for flag -w: must be in the range 1..10`,
+		`required option(s) is missing: --workers`,
+	}
+	got := make([]string, 0, len(errs))
+	for _, e := range errs {
+		got = append(got, e.Error())
+	}
+	if fmt.Sprint(got) != fmt.Sprint(wantErrs) {
+		t.Errorf("incorrect list of errors:\nwant - %#v\ngot  - %#v", wantErrs, got)
+	}
+}
+
+func TestValidatorsUsage(t *testing.T) {
+	t.Parallel()
+
+	tOut := &bytes.Buffer{}
+	p := newParser(stubApp).SetOutput(tOut)
+	p.AddInt("workers|w", "number of workers", new(int), 4)
+	p.AddDuration("timeout", "timeout of operations", new(time.Duration), time.Second)
+	p.AddString("name", "name of instance", new(string), "main")
+	p.AddFunc("hook", "hook to run", func(string) error { return nil }, "cmd")
+	p.
+		SetValidators("workers", Range(1, 256)).
+		SetValidators("timeout", Positive[time.Duration](), Max(time.Hour)).
+		SetValidators("name", NonEmpty(), Match(regexp.MustCompile(`^[a-z]+$`))).
+		SetValidators("hook", Validate("", func(string) error { return nil }))
+	p.SetEnv("workers", "WORKERS")
+	p.Usage()
+
+	want := `
+Usage of ` + stubApp + `:
+    --workers int, -w int
+      number of workers (range: 1..256, env: WORKERS, default: 4)
+    --timeout duration
+      timeout of operations (positive, max: 1h0m0s, default: 1s)
+    --name string
+      name of instance (non-empty, format: ^[a-z]+$, default: main)
+    --hook cmd
+      hook to run (default: "")
+`
+	if tOut.String() != want {
+		t.Errorf("incorrect usage output: want -\n%s\ngot -\n%s", want, tOut.String())
+	}
+}
+
+func TestValidatorsPanic(t *testing.T) {
+	t.Parallel()
+
+	for testN, add := range map[string]func(p *OptsParser){
+		`unknown-option`:	func(p *OptsParser) { p.SetValidators("threads", Min(1)) },
+		`wrong-type`:		func(p *OptsParser) { p.SetValidators("workers", Min(int64(1))) },
+		`string-check`:		func(p *OptsParser) { p.SetValidators("timeout", NonEmpty()) },
+	} {
+		func() {
+			defer func() {
+				if _, ok := recover().(OptsPanic); !ok {
+					t.Errorf("%q must panic with OptsPanic", testN)
+				}
+			}()
+
+			add(validOpts(newTestParser(nil), &testValidOpts{}))
+		}()
+	}
+}
@@ -0,0 +1,528 @@
+package optsparser
+
+import (
+	"encoding"
+	"errors"
+	"flag"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The same errors as returned by the standard flag package on invalid values
+var (
+	errParse = errors.New("parse error")
+	errRange = errors.New("value out of range")
+)
+
+func numError(err error) error {
+	var ne *strconv.NumError
+	if errors.As(err, &ne) && errors.Is(ne.Err, strconv.ErrRange) {
+		return errRange
+	}
+
+	return errParse
+}
+
+// saver is implemented by values which state is not fully kept by the variable they point to
+type saver interface {
+	save() func()
+}
+
+// saveValue saves the current state of the value and returns the function that restores it
+func saveValue(v flag.Value) func() {
+	if s, ok := v.(saver); ok {
+		return s.save()
+	}
+
+	// Values of the standard flag package and most of user values are pointers to variables
+	return saveVar(v)
+}
+
+// saveVar saves the variable pointed by ptr and returns the function that restores it,
+// nothing is restored if ptr is not a pointer
+func saveVar(ptr any) func() {
+	rv := reflect.ValueOf(ptr)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return func() {}
+	}
+
+	elem := rv.Elem()
+	old := reflect.New(elem.Type()).Elem()
+	old.Set(elem)
+
+	return func() { elem.Set(old) }
+}
+
+// counterValue counts occurrences of an option, each occurrence changes the value by step
+type counterValue struct {
+	val		*int
+	step	int
+}
+
+func (c *counterValue) IsBoolFlag() bool {
+	return true
+}
+
+func (c *counterValue) Set(s string) error {
+	// Option is specified without value
+	if s == "true" {
+		*c.val += c.step
+		return nil
+	}
+
+	// Explicit number of occurrences
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return numError(err)
+	}
+	*c.val += c.step * n
+
+	return nil
+}
+
+func (c *counterValue) Get() any {
+	return *c.val
+}
+
+func (c *counterValue) save() func() {
+	return saveVar(c.val)
+}
+
+func (c *counterValue) String() string {
+	if c == nil || c.val == nil {
+		return "0"
+	}
+
+	return strconv.Itoa(*c.val)
+}
+
+// multiValue is implemented by options that can accept multiple values
+// in the single argument, separated by delimiter
+type multiValue interface {
+	delimiter() string
+	setDelimiter(delim string)
+}
+
+// sliceValue collects all values of an option to the slice. The first explicit value
+// replaces the default value, all subsequent values are appended to the slice
+type sliceValue[T any] struct {
+	val		*[]T
+	parse	func(string) (T, error)
+	format	func(T) string
+	delim	string
+	set		bool
+}
+
+func (s *sliceValue[T]) Set(str string) error {
+	items := []string{str}
+	if s.delim != "" {
+		items = strings.Split(str, s.delim)
+	}
+
+	values := make([]T, 0, len(items))
+	for _, item := range items {
+		v, err := s.parse(item)
+		if err != nil {
+			return err
+		}
+		values = append(values, v)
+	}
+
+	// Is it the first explicit value?
+	if !s.set {
+		// Replace the default value
+		*s.val = []T{}
+		s.set = true
+	}
+	*s.val = append(*s.val, values...)
+
+	return nil
+}
+
+func (s *sliceValue[T]) Get() any {
+	return *s.val
+}
+
+func (s *sliceValue[T]) String() string {
+	if s == nil || s.val == nil {
+		return "[]"
+	}
+
+	items := make([]string, 0, len(*s.val))
+	for _, v := range *s.val {
+		items = append(items, s.format(v))
+	}
+
+	return "[" + strings.Join(items, ", ") + "]"
+}
+
+func (s *sliceValue[T]) save() func() {
+	old, set := *s.val, s.set
+
+	return func() { *s.val, s.set = old, set }
+}
+
+func (s *sliceValue[T]) newSource() {
+	s.set = false
+}
+
+func (s *sliceValue[T]) delimiter() string {
+	return s.delim
+}
+
+func (s *sliceValue[T]) setDelimiter(delim string) {
+	s.delim = delim
+}
+
+//
+// Parsers and formatters of values of supported types
+//
+
+func parseString(s string) (string, error) {
+	return s, nil
+}
+
+func parseInt(s string) (int, error) {
+	v, err := strconv.ParseInt(s, 0, strconv.IntSize)
+	if err != nil {
+		return 0, numError(err)
+	}
+
+	return int(v), nil
+}
+
+func parseInt64(s string) (int64, error) {
+	v, err := strconv.ParseInt(s, 0, 64)
+	if err != nil {
+		return 0, numError(err)
+	}
+
+	return v, nil
+}
+
+func parseUint(s string) (uint, error) {
+	v, err := strconv.ParseUint(s, 0, strconv.IntSize)
+	if err != nil {
+		return 0, numError(err)
+	}
+
+	return uint(v), nil
+}
+
+func parseUint64(s string) (uint64, error) {
+	v, err := strconv.ParseUint(s, 0, 64)
+	if err != nil {
+		return 0, numError(err)
+	}
+
+	return v, nil
+}
+
+func parseFloat64(s string) (float64, error) {
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, numError(err)
+	}
+
+	return v, nil
+}
+
+func parseDuration(s string) (time.Duration, error) {
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, errParse
+	}
+
+	return v, nil
+}
+
+func formatAny[T any](v T) string {
+	return fmt.Sprint(v)
+}
+
+// mapValue collects key=value pairs of an option to the map. The first explicit value
+// replaces the default value, all subsequent pairs are added to the map
+type mapValue[T any] struct {
+	val		*map[string]T
+	parse	func(string) (T, error)
+	format	func(T) string
+	delim	string
+	dups	DupKeyPolicy
+	set		bool
+}
+
+func (m *mapValue[T]) Set(str string) error {
+	items := []string{str}
+	if m.delim != "" {
+		items = strings.Split(str, m.delim)
+	}
+
+	// Parse all pairs before changing the map
+	keys := make([]string, 0, len(items))
+	values := make([]T, 0, len(items))
+	for _, item := range items {
+		key, val, ok := strings.Cut(item, "=")
+		if !ok || key == "" {
+			return fmt.Errorf("malformed pair %q, want key=value", item)
+		}
+
+		v, err := m.parse(val)
+		if err != nil {
+			return fmt.Errorf("invalid value of key %q: %w", key, err)
+		}
+
+		keys = append(keys, key)
+		values = append(values, v)
+	}
+
+	// The first explicit value replaces the default value
+	dst := *m.val
+	if !m.set {
+		dst = map[string]T{}
+	}
+
+	// Check all keys for duplicates before changing the map
+	pairs := make(map[string]T, len(keys))
+	for i, key := range keys {
+		_, exists := dst[key]
+		_, added := pairs[key]
+		if exists || added {
+			switch m.dups {
+			case DupKeyFirst:
+				// Keep the existing value
+				continue
+			case DupKeyError:
+				return fmt.Errorf("duplicate key %q", key)
+			case DupKeyLast:
+				// Replace the existing value
+			}
+		}
+
+		pairs[key] = values[i]
+	}
+
+	for key, v := range pairs {
+		dst[key] = v
+	}
+	*m.val, m.set = dst, true
+
+	return nil
+}
+
+func (m *mapValue[T]) Get() any {
+	return *m.val
+}
+
+func (m *mapValue[T]) String() string {
+	if m == nil || m.val == nil {
+		return "{}"
+	}
+
+	pairs := make([]string, 0, len(*m.val))
+	for key, v := range *m.val {
+		pairs = append(pairs, key + "=" + m.format(v))
+	}
+	sort.Strings(pairs)
+
+	return "{" + strings.Join(pairs, ", ") + "}"
+}
+
+func (m *mapValue[T]) save() func() {
+	// Pairs are added to the existing map, so its content has to be copied
+	old, set := *m.val, m.set
+	pairs := make(map[string]T, len(old))
+	for key, v := range old {
+		pairs[key] = v
+	}
+
+	return func() {
+		for key := range old {
+			delete(old, key)
+		}
+		for key, v := range pairs {
+			old[key] = v
+		}
+		*m.val, m.set = old, set
+	}
+}
+
+func (m *mapValue[T]) newSource() {
+	m.set = false
+}
+
+func (m *mapValue[T]) delimiter() string {
+	return m.delim
+}
+
+func (m *mapValue[T]) setDelimiter(delim string) {
+	m.delim = delim
+}
+
+func (m *mapValue[T]) setDupKeyPolicy(policy DupKeyPolicy) {
+	m.dups = policy
+}
+
+func parseBool(s string) (bool, error) {
+	v, err := strconv.ParseBool(s)
+	if err != nil {
+		return false, errParse
+	}
+
+	return v, nil
+}
+
+// scalarParser returns the parser and the name of the type T
+func scalarParser[T Scalar]() (func(string) (T, error), string) {
+	var zero T
+	switch any(zero).(type) {
+	case string:
+		return convParser[T](parseString), typeString
+	case bool:
+		return convParser[T](parseBool), typeBool
+	case int:
+		return convParser[T](parseInt), typeInt
+	case int64:
+		return convParser[T](parseInt64), typeInt64
+	case uint:
+		return convParser[T](parseUint), typeUint
+	case uint64:
+		return convParser[T](parseUint64), typeUint64
+	case float64:
+		return convParser[T](parseFloat64), typeFloat64
+	case time.Duration:
+		return convParser[T](parseDuration), typeDuration
+	}
+
+	// Unreachable because of the type constraint
+	panic("unsupported scalar type")
+}
+
+func convParser[T, V any](parse func(string) (V, error)) func(string) (T, error) {
+	return func(s string) (T, error) {
+		v, err := parse(s)
+		if err != nil {
+			var zero T
+			return zero, err
+		}
+
+		return any(v).(T), nil //nolint:forcetypeassert // V and T are the same type
+	}
+}
+
+// choiceValue accepts only values from the predefined list of choices
+type choiceValue[T ~string] struct {
+	val		*T
+	choices	[]T
+	descrs	map[T]string
+	fold	bool
+}
+
+func (c *choiceValue[T]) Set(s string) error {
+	for _, choice := range c.choices {
+		if string(choice) == s || (c.fold && strings.EqualFold(string(choice), s)) {
+			// Always use the value as it defined in the list of choices
+			*c.val = choice
+			return nil
+		}
+	}
+
+	return fmt.Errorf("invalid choice %q, must be one of: %s", s, c.list(", "))
+}
+
+func (c *choiceValue[T]) Get() any {
+	return *c.val
+}
+
+func (c *choiceValue[T]) save() func() {
+	return saveVar(c.val)
+}
+
+func (c *choiceValue[T]) String() string {
+	if c == nil || c.val == nil {
+		return ""
+	}
+
+	return string(*c.val)
+}
+
+func (c *choiceValue[T]) list(sep string) string {
+	items := make([]string, 0, len(c.choices))
+	for _, choice := range c.choices {
+		items = append(items, string(choice))
+	}
+
+	return strings.Join(items, sep)
+}
+
+func (c *choiceValue[T]) setChoiceDescr(choice, descr string) bool {
+	for _, v := range c.choices {
+		if string(v) == choice {
+			c.descrs[v] = descr
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *choiceValue[T]) setIgnoreCase(v bool) {
+	c.fold = v
+}
+
+// choicesDescr returns descriptions of choices in the form "choice - description"
+func (c *choiceValue[T]) choicesDescr() []string {
+	descrs := make([]string, 0, len(c.descrs))
+	for _, choice := range c.choices {
+		if descr, ok := c.descrs[choice]; ok {
+			descrs = append(descrs, string(choice) + " - " + descr)
+		}
+	}
+
+	return descrs
+}
+
+// textValue adapts encoding.TextUnmarshaler to flag.Value
+type textValue struct {
+	val	encoding.TextUnmarshaler
+}
+
+func (t *textValue) Set(s string) error {
+	return t.val.UnmarshalText([]byte(s))	//nolint:wrapcheck // error is wrapped by the parser
+}
+
+func (t *textValue) Get() any {
+	return t.val
+}
+
+func (t *textValue) save() func() {
+	return saveVar(t.val)
+}
+
+func (t *textValue) String() string {
+	if t == nil || t.val == nil {
+		return ""
+	}
+
+	if m, ok := t.val.(encoding.TextMarshaler); ok {
+		if b, err := m.MarshalText(); err == nil {
+			return string(b)
+		}
+	}
+
+	return ""
+}
+
+// funcValue calls the function on each value of an option
+type funcValue func(string) error
+
+func (f funcValue) Set(s string) error {
+	return f(s)
+}
+
+func (f funcValue) String() string {
+	return ""
+}
@@ -0,0 +1,77 @@
+package optsparser
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCounter(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct{
+		args	[]string
+		want	int
+	}{
+		{ args: []string{},								want: 1 },
+		{ args: []string{`-v`},							want: 2 },
+		{ args: []string{`-vvv`},						want: 4 },
+		{ args: []string{`--verbose`, `--verbose`},		want: 3 },
+		{ args: []string{`-vvx`, `--verbose`},			want: 4 },
+		{ args: []string{`--verbose=3`, `-v`},			want: 5 },
+		{ args: []string{`-qq`},						want: -1 },
+		{ args: []string{`-vvvq`, `--quiet`},			want: 2 },
+	}
+
+	for i, test := range tests {
+		p := newParser(stubApp).SetUsageOnFail(false).SetOutput(&bytes.Buffer{})
+
+		var level int
+		var extract bool
+		p.AddCounter("verbose|v", "increase verbosity", &level, 1)
+		p.AddDecCounter("quiet|q", "decrease verbosity", &level)
+		p.AddBool("extract|x", "extract files", &extract, false)
+
+		if err := p.ParseArgs(test.args); err != nil {
+			t.Errorf("[%d] parse of %#v failed: %v", i, test.args, err)
+			continue
+		}
+		if level != test.want {
+			t.Errorf("[%d] incorrect Parse result of %#v: want - %d got - %d", i, test.args, test.want, level)
+		}
+	}
+
+	// Invalid number of occurrences
+	p := newParser(stubApp).SetUsageOnFail(false).SetOutput(&bytes.Buffer{})
+	p.AddCounter("verbose|v", "increase verbosity", new(int), 0)
+	want := `invalid value "x" for flag --verbose: parse error`
+	if err := p.ParseArgs([]string{`--verbose=x`}); err == nil || err.Error() != want {
+		t.Errorf("parse returned unexpected error: want - %q got - %v", want, err)
+	}
+}
+
+func TestCounterUsage(t *testing.T) {
+	t.Parallel()
+
+	tOut := &bytes.Buffer{}
+	p := newParser(stubApp).SetOutput(tOut)
+
+	var level int
+	p.AddCounter("verbose|v", "increase verbosity", &level, 1)
+	p.AddDecCounter("q", "decrease verbosity", &level)
+	p.Usage()
+
+	want := `
+Usage of ` + stubApp + `:
+    --verbose, -v
+      increase verbosity (can be repeated, default: 1)
+    -q
+      decrease verbosity (can be repeated, default: 1)
+`
+	if tOut.String() != want {
+		t.Errorf("output produced by Usage is different from expexted, see below:\n" +
+			"\n-------- Want --------\n%s\n" +
+			"-------- Got --------\n%s\n",
+			want, tOut.String(),
+		)
+	}
+}